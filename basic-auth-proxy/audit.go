@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"github.com/syumai/basic-auth-proxy/proxy"
+	"github.com/syumai/workers/cloudflare"
+)
+
+// auditBucketName is an R2 bucket binding receiving access records as NDJSON batches.
+const auditBucketName = "AUDIT_LOG"
+
+// r2BatchWriter is a proxy.BatchWriter putting batches to the AUDIT_LOG bucket.
+type r2BatchWriter struct{}
+
+func (r2BatchWriter) WriteBatch(ctx context.Context, key string, ndjson []byte) error {
+	bucket, err := cloudflare.NewR2Bucket(ctx, auditBucketName)
+	if err != nil {
+		return err
+	}
+	_, err = bucket.Put(key, io.NopCloser(bytes.NewReader(ndjson)), &cloudflare.R2PutOptions{
+		HTTPMetadata: cloudflare.R2HTTPMetadata{ContentType: "application/x-ndjson"},
+	})
+	return err
+}
+
+// auditSink buffers records across requests handled by this isolate.
+// Workers keep waitUntil tasks alive for up to 30 seconds after the response,
+// so batches are due well before that.
+var auditSink = proxy.NewAuditSink(r2BatchWriter{}, proxy.AuditSinkConfig{
+	Prefix: "access/",
+	MaxAge: 20 * time.Second,
+})
+
+// auditLogger sends records to auditSink after the response is returned,
+// so that writing a batch to R2 does not delay the response.
+// The request then waits for its batch to be due, so that the records are
+// written even if this isolate receives no further requests.
+var auditLogger = proxy.AccessLoggerFunc(func(ctx context.Context, rec proxy.AccessRecord) {
+	cloudflare.WaitUntil(ctx, func() {
+		auditSink.LogAccess(ctx, rec)
+		if err := auditSink.FlushWhenDue(ctx); err != nil {
+			log.Printf("failed to flush audit records: %v", err)
+		}
+	})
+})
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/syumai/basic-auth-proxy/proxy"
+	"github.com/syumai/workers/cloudflare"
+)
+
+// settings holds the proxy configuration read from vars and secrets.
+// It is comparable so that a proxy is rebuilt only when it changes.
+type settings struct {
+	// Upstream is UPSTREAM_URL.
+	Upstream string
+	// Routes is ROUTES, a routing table in JSON. It takes precedence over Upstream.
+	Routes string
+	// AuthMode is AUTH_MODE, either "basic" (default) or "oidc".
+	AuthMode string
+
+	OIDCIssuer              string
+	OIDCClientID            string
+	OIDCClientSecret        string
+	OIDCRedirectURL         string
+	OIDCAllowedGroups       string // comma separated
+	OIDCAllowedEmailDomains string // comma separated
+	SessionKey              string
+
+	// RateLimit is RATE_LIMIT_RPS, the requests per second allowed from a client IP.
+	RateLimit string
+	// RateLimitBurst is RATE_LIMIT_BURST.
+	RateLimitBurst string
+	// MaxAuthFailures is MAX_AUTH_FAILURES, the failed logins before a lockout.
+	MaxAuthFailures string
+	// HasRateLimitKV reports whether the RATE_LIMIT KV namespace is bound.
+	HasRateLimitKV bool
+	// HasAuditBucket reports whether the AUDIT_LOG R2 bucket is bound.
+	HasAuditBucket bool
+}
+
+func loadSettings(ctx context.Context) settings {
+	return settings{
+		Upstream:                getenv(ctx, "UPSTREAM_URL"),
+		Routes:                  getenv(ctx, "ROUTES"),
+		AuthMode:                getenv(ctx, "AUTH_MODE"),
+		OIDCIssuer:              getenv(ctx, "OIDC_ISSUER"),
+		OIDCClientID:            getenv(ctx, "OIDC_CLIENT_ID"),
+		OIDCClientSecret:        getenv(ctx, "OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:         getenv(ctx, "OIDC_REDIRECT_URL"),
+		OIDCAllowedGroups:       getenv(ctx, "OIDC_ALLOWED_GROUPS"),
+		OIDCAllowedEmailDomains: getenv(ctx, "OIDC_ALLOWED_EMAIL_DOMAINS"),
+		SessionKey:              getenv(ctx, "SESSION_KEY"),
+		RateLimit:               getenv(ctx, "RATE_LIMIT_RPS"),
+		RateLimitBurst:          getenv(ctx, "RATE_LIMIT_BURST"),
+		MaxAuthFailures:         getenv(ctx, "MAX_AUTH_FAILURES"),
+		HasRateLimitKV:          !cloudflare.GetBinding(ctx, rateLimitKVName).IsUndefined(),
+		HasAuditBucket:          !cloudflare.GetBinding(ctx, auditBucketName).IsUndefined(),
+	}
+}
+
+// getenv returns the named var, or "" if it is not set.
+func getenv(ctx context.Context, name string) string {
+	v := cloudflare.GetBinding(ctx, name)
+	if v.IsUndefined() {
+		return ""
+	}
+	return v.String()
+}
+
+func (s settings) proxyConfig() (proxy.Config, error) {
+	cfg := proxy.Config{
+		Transport: transport,
+		AccessLog: proxy.SlogAccessLogger(nil),
+	}
+	if s.HasAuditBucket {
+		cfg.AccessLog = proxy.MultiAccessLogger(cfg.AccessLog, auditLogger)
+	}
+	if s.Routes != "" {
+		routes, err := proxy.ParseRoutes([]byte(s.Routes))
+		if err != nil {
+			return proxy.Config{}, err
+		}
+		cfg.Routes = routes
+	} else {
+		u, err := url.Parse(s.Upstream)
+		if err != nil {
+			return proxy.Config{}, err
+		}
+		cfg.Upstream = u
+	}
+	switch s.AuthMode {
+	case "", "basic":
+		cfg.Store = runtimeStore{}
+	case "oidc":
+		auth, err := proxy.NewOIDC(proxy.OIDCConfig{
+			Issuer:              s.OIDCIssuer,
+			ClientID:            s.OIDCClientID,
+			ClientSecret:        s.OIDCClientSecret,
+			RedirectURL:         s.OIDCRedirectURL,
+			AllowedGroups:       splitList(s.OIDCAllowedGroups),
+			AllowedEmailDomains: splitList(s.OIDCAllowedEmailDomains),
+			SessionKey:          []byte(s.SessionKey),
+			HTTPClient:          &http.Client{Transport: transport},
+		})
+		if err != nil {
+			return proxy.Config{}, err
+		}
+		cfg.Auth = auth
+	default:
+		return proxy.Config{}, fmt.Errorf("unknown AUTH_MODE %q", s.AuthMode)
+	}
+	rl, err := s.rateLimitConfig()
+	if err != nil {
+		return proxy.Config{}, err
+	}
+	cfg.RateLimit = rl
+	return cfg, nil
+}
+
+// rateLimitConfig returns the rate limit config, defaulting to
+// 10 requests per second with bursts of 20, and a lockout after 5 failures.
+func (s settings) rateLimitConfig() (*proxy.RateLimitConfig, error) {
+	rate, err := parseNumber(s.RateLimit, 10)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_RPS: %w", err)
+	}
+	burst, err := parseNumber(s.RateLimitBurst, 20)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_BURST: %w", err)
+	}
+	maxFailures, err := parseNumber(s.MaxAuthFailures, 5)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAX_AUTH_FAILURES: %w", err)
+	}
+	var store proxy.CounterStore = memoryCounters
+	if s.HasRateLimitKV {
+		store = kvCounterStore{}
+	}
+	return &proxy.RateLimitConfig{
+		Store:       store,
+		Buckets:     memoryBuckets,
+		Rate:        rate,
+		Burst:       int(burst),
+		MaxFailures: int(maxFailures),
+	}, nil
+}
+
+// parseNumber parses s, returning def if s is empty.
+func parseNumber(s string, def float64) (float64, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
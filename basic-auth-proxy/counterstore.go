@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/syumai/basic-auth-proxy/proxy"
+	"github.com/syumai/workers/cloudflare"
+)
+
+// rateLimitKVName is a KV namespace binding holding failed login counters.
+// Without it, they are kept in memory of each isolate.
+const rateLimitKVName = "RATE_LIMIT"
+
+// kvMinTTL is the minimum expiration TTL accepted by KV.
+const kvMinTTL = 60 * time.Second
+
+// kvCounterStore is a proxy.CounterStore on KV. KV is eventually consistent
+// and allows about one write per second to a key, so counters may lag behind
+// for requests handled in other locations. It is only used for failed logins,
+// which are rare enough for this; token buckets stay in memoryBuckets.
+type kvCounterStore struct{}
+
+var _ proxy.CounterStore = kvCounterStore{}
+
+func (kvCounterStore) Load(ctx context.Context, key string) (proxy.CounterState, error) {
+	kv, err := cloudflare.NewKVNamespace(ctx, rateLimitKVName)
+	if err != nil {
+		return proxy.CounterState{}, err
+	}
+	v, err := kv.GetString(key, nil)
+	if err != nil {
+		return proxy.CounterState{}, err
+	}
+	var s proxy.CounterState
+	// GetString converts the null returned for a missing key to "<null>".
+	if v == "<null>" || v == "" {
+		return s, nil
+	}
+	err = json.Unmarshal([]byte(v), &s)
+	return s, err
+}
+
+func (kvCounterStore) Store(ctx context.Context, key string, s proxy.CounterState, ttl time.Duration) error {
+	kv, err := cloudflare.NewKVNamespace(ctx, rateLimitKVName)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return kv.PutString(key, string(b), &cloudflare.KVNamespacePutOptions{
+		ExpirationTTL: int(math.Ceil(max(ttl, kvMinTTL).Seconds())),
+	})
+}
+
+// memoryCounters keeps failed login counters across proxy rebuilds when KV is not bound.
+var memoryCounters = proxy.NewMemoryCounterStore()
+
+// memoryBuckets keeps the token buckets across proxy rebuilds.
+// They are per isolate, so a client spread over several isolates
+// may send more than the configured rate in total.
+var memoryBuckets = proxy.NewMemoryCounterStore()
@@ -0,0 +1,3 @@
+// Package fetch provides an http.RoundTripper backed by the fetch API of the Workers runtime.
+// It is only available on js/wasm; use an httptest.Server and its client transport in native tests.
+package fetch
@@ -0,0 +1,334 @@
+//go:build js && wasm
+
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall/js"
+	"time"
+)
+
+var (
+	objectClass          = js.Global().Get("Object")
+	promiseClass         = js.Global().Get("Promise")
+	headersClass         = js.Global().Get("Headers")
+	uint8ArrayClass      = js.Global().Get("Uint8Array")
+	readableStreamClass  = js.Global().Get("ReadableStream")
+	abortControllerClass = js.Global().Get("AbortController")
+	errorClass           = js.Global().Get("Error")
+)
+
+// RedirectMode is the redirect option of a fetch request.
+type RedirectMode string
+
+const (
+	// RedirectManual returns redirect responses to the caller as is.
+	RedirectManual RedirectMode = "manual"
+	// RedirectFollow follows redirects.
+	RedirectFollow RedirectMode = "follow"
+	// RedirectError fails the request on a redirect response.
+	RedirectError RedirectMode = "error"
+)
+
+// ErrTimeout is returned when the response headers are not received within Transport.Timeout.
+var ErrTimeout = errors.New("fetch: timeout awaiting response headers")
+
+// Transport is an http.RoundTripper sending requests with fetch.
+// Request and response bodies are streamed, and canceling the request context
+// aborts the fetch including an in-flight response body.
+type Transport struct {
+	// Fetch is the fetch function to call, such as the fetch method of a service binding.
+	// If undefined, the global fetch is used.
+	Fetch js.Value
+	// Redirect is the redirect mode. If empty, RedirectManual is used.
+	Redirect RedirectMode
+	// Timeout limits the time to wait for the response headers.
+	// Zero means no timeout.
+	Timeout time.Duration
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fetch := t.Fetch
+	if fetch.IsUndefined() {
+		fetch = js.Global().Get("fetch")
+	}
+	redirect := t.Redirect
+	if redirect == "" {
+		redirect = RedirectManual
+	}
+	ctx := req.Context()
+	if err := ctx.Err(); err != nil {
+		closeBody(req)
+		return nil, err
+	}
+
+	controller := abortControllerClass.New()
+	abort := func(reason error) {
+		controller.Call("abort", errorClass.New(reason.Error()))
+	}
+	stopCtx := context.AfterFunc(ctx, func() { abort(ctx.Err()) })
+
+	// headersReceived and timedOut are guarded by mu so that
+	// the timer never aborts a fetch whose response is returned.
+	var (
+		mu              sync.Mutex
+		headersReceived bool
+		timedOut        bool
+	)
+	if t.Timeout > 0 {
+		timer := time.AfterFunc(t.Timeout, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if !headersReceived {
+				timedOut = true
+				abort(ErrTimeout)
+			}
+		})
+		defer timer.Stop()
+	}
+
+	init := objectClass.New()
+	init.Set("method", req.Method)
+	host := req.Host
+	if host == req.URL.Host {
+		host = ""
+	}
+	init.Set("headers", toJSHeaders(req.Header, host))
+	init.Set("redirect", string(redirect))
+	init.Set("signal", controller.Get("signal"))
+	if req.Body != nil && req.Body != http.NoBody {
+		init.Set("body", newBodyStream(req.Body))
+		init.Set("duplex", "half")
+	} else {
+		closeBody(req)
+	}
+
+	v, err := await(fetch.Invoke(req.URL.String(), init))
+	mu.Lock()
+	headersReceived = true
+	mu.Unlock()
+	switch {
+	case timedOut:
+		err = ErrTimeout
+	case ctx.Err() != nil:
+		err = ctx.Err()
+	case err != nil:
+		err = fmt.Errorf("fetch: %w", err)
+	}
+	if err != nil {
+		stopCtx()
+		return nil, err
+	}
+	return toResponse(req, v, stopCtx), nil
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// toJSHeaders converts h to Headers. host overrides the Host header if set.
+func toJSHeaders(h http.Header, host string) js.Value {
+	headers := headersClass.New()
+	for key, values := range h {
+		for _, v := range values {
+			headers.Call("append", key, v)
+		}
+	}
+	if host != "" {
+		headers.Call("set", "Host", host)
+	}
+	return headers
+}
+
+func toResponse(req *http.Request, v js.Value, stopCtx func() bool) *http.Response {
+	header := http.Header{}
+	it := v.Get("headers").Call("entries")
+	for {
+		next := it.Call("next")
+		if next.Get("done").Bool() {
+			break
+		}
+		entry := next.Get("value")
+		header.Add(entry.Index(0).String(), entry.Index(1).String())
+	}
+	contentLength := int64(-1)
+	if n, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+		contentLength = n
+	}
+	var body io.ReadCloser = http.NoBody
+	if b := v.Get("body"); !b.IsNull() && !b.IsUndefined() {
+		body = &bodyReader{ctx: req.Context(), reader: b.Call("getReader"), stopCtx: stopCtx}
+	} else {
+		stopCtx()
+	}
+	status := v.Get("status").Int()
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + v.Get("statusText").String(),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          body,
+		ContentLength: contentLength,
+		Request:       req,
+	}
+}
+
+// bodyReader reads a response body from a ReadableStreamDefaultReader.
+type bodyReader struct {
+	ctx     context.Context
+	reader  js.Value
+	chunk   js.Value
+	offset  int
+	err     error
+	stopCtx func() bool
+	once    sync.Once
+}
+
+func (r *bodyReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for r.chunk.IsUndefined() || r.offset >= r.chunk.Length() {
+		if r.err != nil {
+			return 0, r.err
+		}
+		result, err := await(r.reader.Call("read"))
+		if err != nil {
+			if r.ctx.Err() != nil {
+				err = r.ctx.Err()
+			}
+			r.err = err
+			r.release()
+			return 0, err
+		}
+		if result.Get("done").Bool() {
+			r.err = io.EOF
+			r.release()
+			return 0, io.EOF
+		}
+		r.chunk, r.offset = result.Get("value"), 0
+	}
+	end := min(r.offset+len(p), r.chunk.Length())
+	n := js.CopyBytesToGo(p, r.chunk.Call("subarray", r.offset, end))
+	r.offset += n
+	return n, nil
+}
+
+func (r *bodyReader) Close() error {
+	if r.err == nil {
+		r.err = errors.New("fetch: read on closed body")
+		r.reader.Call("cancel").Call("catch", noop)
+	}
+	r.release()
+	return nil
+}
+
+func (r *bodyReader) release() {
+	r.once.Do(func() { r.stopCtx() })
+}
+
+// newBodyStream returns a ReadableStream pulling from body.
+func newBodyStream(body io.ReadCloser) js.Value {
+	var pull, cancel js.Func
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			body.Close()
+			pull.Release()
+			cancel.Release()
+		})
+	}
+	buf := make([]byte, 32*1024)
+	pull = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		controller := args[0]
+		return newPromise(func(resolve, reject js.Value) {
+			n, err := body.Read(buf)
+			if n > 0 {
+				chunk := uint8ArrayClass.New(n)
+				js.CopyBytesToJS(chunk, buf[:n])
+				controller.Call("enqueue", chunk)
+			}
+			switch {
+			case err == io.EOF:
+				controller.Call("close")
+				release()
+			case err != nil:
+				controller.Call("error", errorClass.New(err.Error()))
+				release()
+			}
+			resolve.Invoke()
+		})
+	})
+	cancel = js.FuncOf(func(js.Value, []js.Value) any {
+		go release()
+		return js.Undefined()
+	})
+	source := objectClass.New()
+	source.Set("pull", pull)
+	source.Set("cancel", cancel)
+	return readableStreamClass.New(source)
+}
+
+// newPromise returns a Promise settled by fn, which runs in a new goroutine
+// so that it may block.
+func newPromise(fn func(resolve, reject js.Value)) js.Value {
+	var executor js.Func
+	executor = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		resolve, reject := args[0], args[1]
+		go fn(resolve, reject)
+		executor.Release()
+		return js.Undefined()
+	})
+	return promiseClass.New(executor)
+}
+
+var noop = js.FuncOf(func(js.Value, []js.Value) any { return js.Undefined() })
+
+// await waits for promise to settle.
+func await(promise js.Value) (js.Value, error) {
+	type result struct {
+		v   js.Value
+		err error
+	}
+	ch := make(chan result, 1)
+	var then, catch js.Func
+	then = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		ch <- result{v: args[0]}
+		then.Release()
+		catch.Release()
+		return js.Undefined()
+	})
+	catch = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		ch <- result{err: jsError(args[0])}
+		then.Release()
+		catch.Release()
+		return js.Undefined()
+	})
+	promiseClass.Call("resolve", promise).Call("then", then, catch)
+	r := <-ch
+	return r.v, r.err
+}
+
+func jsError(v js.Value) error {
+	if v.IsNull() || v.IsUndefined() {
+		return errors.New("promise rejected")
+	}
+	if v.Type() == js.TypeObject {
+		if msg := v.Get("message"); msg.Type() == js.TypeString {
+			return errors.New(msg.String())
+		}
+	}
+	return errors.New(v.Call("toString").String())
+}
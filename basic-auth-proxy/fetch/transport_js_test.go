@@ -0,0 +1,141 @@
+//go:build js && wasm
+
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+// stubFetch returns a JS fetch function with the given body.
+func stubFetch(body string) js.Value {
+	return js.Global().Get("Function").New("url", "init", body)
+}
+
+// echoFetch responds with the request body and reports the request in headers.
+var echoFetch = stubFetch(`
+	const req = new Request(url, init);
+	return new Response(req.body, {
+		status: 201,
+		headers: {
+			"X-Method": req.method,
+			"X-Url": req.url,
+			"X-Redirect": init.redirect,
+			"X-Custom": req.headers.get("X-Custom"),
+		},
+	});
+`)
+
+// hangingFetch never responds until aborted.
+var hangingFetch = stubFetch(`
+	return new Promise((_, reject) => {
+		init.signal.addEventListener("abort", () => reject(init.signal.reason));
+	});
+`)
+
+func TestRoundTrip(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		for _, s := range []string{"hello, ", "streaming ", "world"} {
+			pw.Write([]byte(s))
+		}
+		pw.Close()
+	}()
+	req, _ := http.NewRequest("POST", "https://example.com/path?q=1", pr)
+	req.Header.Set("X-Custom", "value")
+	resp, err := (&Transport{Fetch: echoFetch}).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+	for key, want := range map[string]string{
+		"X-Method":   "POST",
+		"X-Url":      "https://example.com/path?q=1",
+		"X-Redirect": "manual",
+		"X-Custom":   "value",
+	} {
+		if got := resp.Header.Get(key); got != want {
+			t.Errorf("%s: got %q, want %q", key, got, want)
+		}
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello, streaming world" {
+		t.Errorf("got body %q", b)
+	}
+}
+
+func TestRoundTripRedirectMode(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	resp, err := (&Transport{Fetch: echoFetch, Redirect: RedirectFollow}).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("X-Redirect"); got != "follow" {
+		t.Errorf("got redirect mode %q, want follow", got)
+	}
+}
+
+func TestRoundTripTimeout(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	_, err := (&Transport{Fetch: hangingFetch, Timeout: 10 * time.Millisecond}).RoundTrip(req)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("want ErrTimeout, got %v", err)
+	}
+}
+
+func TestRoundTripContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://example.com/", nil)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := (&Transport{Fetch: hangingFetch}).RoundTrip(req)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want context.Canceled, got %v", err)
+	}
+}
+
+func TestRoundTripCancelBody(t *testing.T) {
+	// The response body never ends until the fetch is aborted.
+	endlessFetch := stubFetch(`
+		const body = new ReadableStream({
+			start(controller) {
+				controller.enqueue(new TextEncoder().encode("partial"));
+				init.signal.addEventListener("abort", () => controller.error(init.signal.reason));
+			},
+		});
+		return new Response(body);
+	`)
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://example.com/", nil)
+	resp, err := (&Transport{Fetch: endlessFetch}).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	time.AfterFunc(10*time.Millisecond, cancel)
+	b, err := io.ReadAll(resp.Body)
+	if string(b) != "partial" || !errors.Is(err, context.Canceled) {
+		t.Errorf("got %q, %v; want partial body and context.Canceled", b, err)
+	}
+}
+
+func TestRoundTripError(t *testing.T) {
+	rejectingFetch := stubFetch(`return Promise.reject(new TypeError("network down"));`)
+	req, _ := http.NewRequest("GET", "https://example.com/", nil)
+	_, err := (&Transport{Fetch: rejectingFetch}).RoundTrip(req)
+	if err == nil || !strings.Contains(err.Error(), "network down") {
+		t.Errorf("want network error, got %v", err)
+	}
+}
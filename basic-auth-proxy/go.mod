@@ -1,10 +1,10 @@
 module github.com/syumai/basic-auth-proxy
 
-go 1.18
-
-require github.com/syumai/workers v0.1.1
+go 1.21.3
 
 require (
-	github.com/alphahorizonio/tinynet v0.0.0-20210118222949-51439cf30be8
-	github.com/alphahorizonio/unisockets v0.1.1 // indirect
+	github.com/syumai/workers v0.23.3
+	golang.org/x/crypto v0.23.0
 )
+
+require golang.org/x/sys v0.20.0 // indirect
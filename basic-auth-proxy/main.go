@@ -2,67 +2,57 @@ package main
 
 import (
 	"context"
-	"io"
-	"net"
+	"log"
 	"net/http"
+	"sync"
+	"time"
 
-	"github.com/alphahorizonio/tinynet/pkg/tinynet"
+	"github.com/syumai/basic-auth-proxy/fetch"
+	"github.com/syumai/basic-auth-proxy/proxy"
 	"github.com/syumai/workers"
 )
 
-const (
-	userName     = "user"
-	userPassword = "password"
-)
-
-type wrappedConn struct {
-	tinynet.Conn
+var transport = &fetch.Transport{
+	Redirect: fetch.RedirectManual,
+	Timeout:  30 * time.Second,
 }
 
-func (c *wrappedConn) LocalAddr() net.Addr {
-	return c.Conn.LocalAddr()
-}
-
-func (c *wrappedConn) RemoteAddr() net.Addr {
-	return c.Conn.LocalAddr()
-}
-
-var transport = &http.Transport{
-	Proxy: http.ProxyFromEnvironment,
-	DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-		conn, err := tinynet.Dial(network, addr)
-		if err != nil {
-			return nil, err
-		}
-		return &wrappedConn{conn}, nil
-	},
-	ForceAttemptHTTP2: false,
-	MaxIdleConns:      100,
-}
-
-var client = http.Client{
-	Transport: transport,
-}
+var (
+	mu             sync.Mutex
+	cachedProxy    *proxy.Proxy
+	cachedSettings settings
+)
 
-func authenticate(req *http.Request) bool {
-	username, password, ok := req.BasicAuth()
-	return ok && username == userName && password == userPassword
+// getProxy returns the proxy for the settings in the environment.
+// The environment is only available in a request context, so the proxy is
+// built on the first request and rebuilt if the settings change.
+func getProxy(ctx context.Context) (*proxy.Proxy, error) {
+	s := loadSettings(ctx)
+	mu.Lock()
+	defer mu.Unlock()
+	if cachedProxy != nil && cachedSettings == s {
+		return cachedProxy, nil
+	}
+	cfg, err := s.proxyConfig()
+	if err != nil {
+		return nil, err
+	}
+	p, err := proxy.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cachedProxy, cachedSettings = p, s
+	return p, nil
 }
 
 func handleRequest(w http.ResponseWriter, req *http.Request) {
-	if !authenticate(req) {
-		w.Header().Add("WWW-Authenticate", `Basic realm="login is required"`)
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte("Unauthorized\n"))
-		return
-	}
-	resp, err := client.Do(req)
+	p, err := getProxy(req.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("InternalServerError\n"))
+		log.Printf("failed to configure proxy: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	io.Copy(w, resp.Body)
+	p.ServeHTTP(w, req)
 }
 
 func main() {
@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AccessRecord is a structured access log record of a request to the proxy.
+type AccessRecord struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user,omitempty"`
+	ClientIP string    `json:"client_ip"`
+	Method   string    `json:"method"`
+	Path     string    `json:"path"`
+	// Query is the query string with secrets redacted.
+	Query string `json:"query,omitempty"`
+	// Status is the status sent to the client.
+	Status int `json:"status"`
+	// UpstreamStatus is the status returned by the upstream,
+	// or zero if the request was not forwarded or failed.
+	UpstreamStatus int `json:"upstream_status,omitempty"`
+	// Latency is the time until the response was fully written.
+	Latency time.Duration `json:"latency_ns"`
+	// Bytes is the size of the response body sent to the client.
+	Bytes int64 `json:"bytes"`
+}
+
+// AccessLogger receives an AccessRecord for each request.
+type AccessLogger interface {
+	LogAccess(ctx context.Context, rec AccessRecord)
+}
+
+// AccessLoggerFunc is an AccessLogger calling itself.
+type AccessLoggerFunc func(ctx context.Context, rec AccessRecord)
+
+func (f AccessLoggerFunc) LogAccess(ctx context.Context, rec AccessRecord) {
+	f(ctx, rec)
+}
+
+// MultiAccessLogger returns an AccessLogger sending records to all loggers.
+func MultiAccessLogger(loggers ...AccessLogger) AccessLogger {
+	return AccessLoggerFunc(func(ctx context.Context, rec AccessRecord) {
+		for _, l := range loggers {
+			l.LogAccess(ctx, rec)
+		}
+	})
+}
+
+// SlogAccessLogger returns an AccessLogger writing records to logger at the info level.
+// If logger is nil, slog.Default() is used.
+func SlogAccessLogger(logger *slog.Logger) AccessLogger {
+	return AccessLoggerFunc(func(ctx context.Context, rec AccessRecord) {
+		l := logger
+		if l == nil {
+			l = slog.Default()
+		}
+		l.LogAttrs(ctx, slog.LevelInfo, "access",
+			slog.String("user", rec.User),
+			slog.String("client_ip", rec.ClientIP),
+			slog.String("method", rec.Method),
+			slog.String("path", rec.Path),
+			slog.String("query", rec.Query),
+			slog.Int("status", rec.Status),
+			slog.Int("upstream_status", rec.UpstreamStatus),
+			slog.Duration("latency", rec.Latency),
+			slog.Int64("bytes", rec.Bytes),
+		)
+	})
+}
+
+// DefaultRedactedParams are the query parameters redacted from access logs by default.
+var DefaultRedactedParams = []string{
+	"access_token", "api_key", "apikey", "auth", "client_secret", "code",
+	"id_token", "key", "password", "refresh_token", "secret", "sig",
+	"signature", "state", "token", "x-amz-credential", "x-amz-signature",
+}
+
+const redacted = "REDACTED"
+
+// RedactQuery replaces the values of params in rawQuery, compared case-insensitively.
+// Parameters are kept in their original order and encoding.
+func RedactQuery(rawQuery string, params []string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		rawKey, _, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+		for _, p := range params {
+			if strings.EqualFold(key, p) {
+				pairs[i] = rawKey + "=" + redacted
+				break
+			}
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// requestState is the state of a request shared by the stages of the proxy.
+type requestState struct {
+	in          *http.Request
+	route       *route
+	forwardPath string
+	// candidates are the upstreams to try in order.
+	candidates []*upstream
+
+	// user and upstreamStatus are recorded for the access log.
+	user           string
+	upstreamStatus int
+}
+
+type requestStateKey struct{}
+
+func requestStateFrom(ctx context.Context) *requestState {
+	st, _ := ctx.Value(requestStateKey{}).(*requestState)
+	return st
+}
+
+// recordingWriter records the status and the body size written to an http.ResponseWriter.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, such as for flushing.
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
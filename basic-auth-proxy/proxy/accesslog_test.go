@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactQuery(t *testing.T) {
+	params := []string{"token", "api_key"}
+	tests := map[string]string{
+		"":                            "",
+		"q=go":                        "q=go",
+		"token=abc&q=go":              "token=REDACTED&q=go",
+		"TOKEN=abc":                   "TOKEN=REDACTED",
+		"api%5Fkey=abc":               "api%5Fkey=REDACTED",
+		"token":                       "token=REDACTED",
+		"q=1&token=a&token=b&x=token": "q=1&token=REDACTED&token=REDACTED&x=token",
+	}
+	for in, want := range tests {
+		if got := RedactQuery(in, params); got != want {
+			t.Errorf("RedactQuery(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var records []AccessRecord
+	p, _ := newTestProxy(t, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "accepted")
+	})
+	p.accessLog = AccessLoggerFunc(func(_ context.Context, rec AccessRecord) {
+		records = append(records, rec)
+	})
+
+	req := newAuthRequest("POST", "https://proxy.example.com/items?access_token=s3cret&page=2", nil)
+	req.Header.Set("CF-Connecting-IP", "203.0.113.7")
+	p.ServeHTTP(httptest.NewRecorder(), req)
+	p.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "https://proxy.example.com/", nil))
+
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	got := records[0]
+	if got.User != "alice" || got.ClientIP != "203.0.113.7" || got.Method != "POST" || got.Path != "/items" {
+		t.Errorf("unexpected record %+v", got)
+	}
+	if got.Query != "access_token=REDACTED&page=2" {
+		t.Errorf("got query %q", got.Query)
+	}
+	if got.Status != http.StatusAccepted || got.UpstreamStatus != http.StatusAccepted || got.Bytes != int64(len("accepted")) {
+		t.Errorf("got status %d, upstream status %d, bytes %d", got.Status, got.UpstreamStatus, got.Bytes)
+	}
+	if got.Latency <= 0 || got.Time.IsZero() {
+		t.Errorf("got latency %v at %v", got.Latency, got.Time)
+	}
+
+	denied := records[1]
+	if denied.User != "" || denied.Status != http.StatusUnauthorized || denied.UpstreamStatus != 0 {
+		t.Errorf("unexpected record for an unauthenticated request %+v", denied)
+	}
+}
+
+type fakeBatchWriter struct {
+	batches map[string][]byte
+	err     error
+}
+
+func (w *fakeBatchWriter) WriteBatch(_ context.Context, key string, ndjson []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.batches[key] = ndjson
+	return nil
+}
+
+func TestAuditSink(t *testing.T) {
+	w := &fakeBatchWriter{batches: map[string][]byte{}}
+	clock := &fakeClock{t: time.Date(2026, 10, 19, 10, 30, 0, 0, time.UTC)}
+	sink := NewAuditSink(w, AuditSinkConfig{Prefix: "audit/", MaxRecords: 3, MaxAge: time.Minute})
+	sink.now = clock.now
+	ctx := context.Background()
+
+	// A failed flush keeps the records for the next batch.
+	w.err = errors.New("r2 unavailable")
+	for i := 0; i < 3; i++ {
+		sink.LogAccess(ctx, AccessRecord{Path: "/" + string(rune('a'+i))})
+	}
+	if len(w.batches) != 0 {
+		t.Fatalf("got %d batches, want none", len(w.batches))
+	}
+	w.err = nil
+	sink.LogAccess(ctx, AccessRecord{Path: "/d"})
+	if len(w.batches) != 1 {
+		t.Fatalf("got %d batches, want 1", len(w.batches))
+	}
+	for key, data := range w.batches {
+		if !strings.HasPrefix(key, "audit/2026/10/19/20261019T103000Z-") || !strings.HasSuffix(key, ".ndjson") {
+			t.Errorf("unexpected key %q", key)
+		}
+		var paths []string
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			var rec AccessRecord
+			if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+				t.Fatal(err)
+			}
+			paths = append(paths, rec.Path)
+		}
+		if strings.Join(paths, ",") != "/a,/b,/c,/d" {
+			t.Errorf("got records %v", paths)
+		}
+	}
+
+	// An old record triggers a flush.
+	sink.LogAccess(ctx, AccessRecord{Path: "/e"})
+	clock.advance(time.Minute)
+	sink.LogAccess(ctx, AccessRecord{Path: "/f"})
+	if len(w.batches) != 2 {
+		t.Errorf("got %d batches, want 2", len(w.batches))
+	}
+	if err := sink.Flush(ctx); err != nil {
+		t.Errorf("flushing an empty sink: %v", err)
+	}
+}
+
+// batchPaths returns the paths of the records in an NDJSON batch.
+func batchPaths(t *testing.T, data []byte) string {
+	t.Helper()
+	var paths []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		var rec AccessRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, rec.Path)
+	}
+	return strings.Join(paths, ",")
+}
+
+func TestAuditSinkFlushWhenDue(t *testing.T) {
+	w := &fakeBatchWriter{batches: map[string][]byte{}}
+	clock := &fakeClock{t: time.Date(2026, 10, 19, 10, 30, 0, 0, time.UTC)}
+	sink := NewAuditSink(w, AuditSinkConfig{MaxRecords: 100, MaxAge: 20 * time.Second})
+	sink.now = clock.now
+	ctx := context.Background()
+
+	var slept []time.Duration
+	sink.sleep = func(ctx context.Context, d time.Duration) {
+		slept = append(slept, d)
+		// A request logged while the first one waits does not wait again.
+		clock.advance(5 * time.Second)
+		sink.LogAccess(ctx, AccessRecord{Path: "/b"})
+		if err := sink.FlushWhenDue(ctx); err != nil || len(slept) != 1 {
+			t.Errorf("second FlushWhenDue: %v after %d sleeps, want to return at once", err, len(slept))
+		}
+		clock.advance(d - 5*time.Second)
+	}
+	sink.LogAccess(ctx, AccessRecord{Path: "/a"})
+	if err := sink.FlushWhenDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(slept) != 1 || slept[0] != 20*time.Second {
+		t.Errorf("slept %v, want [20s]", slept)
+	}
+	if len(w.batches) != 1 {
+		t.Fatalf("got %d batches, want 1 written by the idle instance", len(w.batches))
+	}
+	for _, data := range w.batches {
+		if got := batchPaths(t, data); got != "/a,/b" {
+			t.Errorf("got records %v", got)
+		}
+	}
+	if err := sink.FlushWhenDue(ctx); err != nil || len(slept) != 1 {
+		t.Errorf("FlushWhenDue of an empty sink: %v after %d sleeps", err, len(slept))
+	}
+
+	// A waiter which never returned does not block later ones once its time has passed.
+	sink.sleep = func(context.Context, time.Duration) {}
+	sink.LogAccess(ctx, AccessRecord{Path: "/c"})
+	sink.waitingUntil = clock.now().Add(time.Second)
+	clock.advance(2 * time.Second)
+	sink.sleep = func(_ context.Context, d time.Duration) { clock.advance(d) }
+	if err := sink.FlushWhenDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.batches) != 2 {
+		t.Errorf("got %d batches, want 2", len(w.batches))
+	}
+}
+
+func TestAuditSinkMaxBuffered(t *testing.T) {
+	w := &fakeBatchWriter{batches: map[string][]byte{}, err: errors.New("r2 unavailable")}
+	sink := NewAuditSink(w, AuditSinkConfig{MaxRecords: 2, MaxBuffered: 4})
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		sink.LogAccess(ctx, AccessRecord{Path: "/" + string(rune('a'+i))})
+	}
+	if n := len(sink.lines); n > 4 {
+		t.Errorf("buffered %d records, want at most 4", n)
+	}
+	w.err = nil
+	if err := sink.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range w.batches {
+		if got := batchPaths(t, data); got != "/g,/h,/i,/j" {
+			t.Errorf("got records %v, want the latest 4", got)
+		}
+	}
+}
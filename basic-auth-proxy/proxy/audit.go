@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// BatchWriter stores a batch of access records encoded as NDJSON under key,
+// such as an object in an R2 bucket.
+type BatchWriter interface {
+	WriteBatch(ctx context.Context, key string, ndjson []byte) error
+}
+
+// AuditSinkConfig configures an AuditSink.
+type AuditSinkConfig struct {
+	// Prefix is prepended to the keys of batches.
+	Prefix string
+	// MaxRecords is the number of records that triggers a flush. Defaults to 100.
+	MaxRecords int
+	// MaxAge is the age of the oldest buffered record that triggers a flush. Defaults to 1 minute.
+	MaxAge time.Duration
+	// MaxBuffered is the number of records kept while writes fail.
+	// The oldest records beyond it are dropped. Defaults to 10 times MaxRecords.
+	MaxBuffered int
+}
+
+// AuditSink is an AccessLogger buffering records and writing them in NDJSON batches.
+// A batch is flushed by the first LogAccess call after it is full or old enough,
+// or by FlushWhenDue once it is old enough. Records still buffered when the
+// instance stops are lost.
+type AuditSink struct {
+	w     BatchWriter
+	cfg   AuditSinkConfig
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration)
+
+	mu    sync.Mutex
+	lines [][]byte
+	first time.Time
+	// waitingUntil is when a pending FlushWhenDue call flushes, or zero if there is none.
+	waitingUntil time.Time
+}
+
+var _ AccessLogger = (*AuditSink)(nil)
+
+// NewAuditSink returns an AuditSink writing batches to w.
+func NewAuditSink(w BatchWriter, cfg AuditSinkConfig) *AuditSink {
+	if cfg.MaxRecords <= 0 {
+		cfg.MaxRecords = 100
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = time.Minute
+	}
+	if cfg.MaxBuffered < cfg.MaxRecords {
+		cfg.MaxBuffered = 10 * cfg.MaxRecords
+	}
+	return &AuditSink{w: w, cfg: cfg, now: time.Now, sleep: sleep}
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+func (s *AuditSink) LogAccess(ctx context.Context, rec AccessRecord) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		log.Printf("failed to encode audit record: %v", err)
+		return
+	}
+	s.mu.Lock()
+	if len(s.lines) == 0 {
+		s.first = s.now()
+	}
+	s.lines = append(s.lines, line)
+	full := len(s.lines) >= s.cfg.MaxRecords || s.now().Sub(s.first) >= s.cfg.MaxAge
+	s.mu.Unlock()
+	if full {
+		if err := s.Flush(ctx); err != nil {
+			log.Printf("failed to flush audit records: %v", err)
+		}
+	}
+}
+
+// FlushWhenDue waits until the buffered batch is MaxAge old and flushes it,
+// so that the records of an instance receiving no more requests are written.
+// It returns at once if nothing is buffered or another call is already waiting.
+// On Workers, call it in cloudflare.WaitUntil after each LogAccess, with a MaxAge
+// shorter than the time the runtime keeps waitUntil tasks alive.
+func (s *AuditSink) FlushWhenDue(ctx context.Context) error {
+	// waitingUntil is left in place if this call never returns, such as when the
+	// runtime stops its waitUntil task; another call takes over once it passes.
+	var mine time.Time
+	for {
+		s.mu.Lock()
+		now := s.now()
+		if len(s.lines) == 0 {
+			if !mine.IsZero() && s.waitingUntil.Equal(mine) {
+				s.waitingUntil = time.Time{}
+			}
+			s.mu.Unlock()
+			return nil
+		}
+		if mine.IsZero() && s.waitingUntil.After(now) {
+			s.mu.Unlock()
+			return nil
+		}
+		due := s.first.Add(s.cfg.MaxAge)
+		if !due.After(now) || ctx.Err() != nil {
+			s.waitingUntil = time.Time{}
+			s.mu.Unlock()
+			return s.Flush(context.WithoutCancel(ctx))
+		}
+		s.waitingUntil, mine = due, due
+		s.mu.Unlock()
+		s.sleep(ctx, due.Sub(now))
+	}
+}
+
+// Flush writes the buffered records as a batch. On failure, the records are kept
+// and written with the next batch, up to MaxBuffered records.
+func (s *AuditSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	if len(s.lines) == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	lines, first := s.lines, s.first
+	s.lines = nil
+	s.mu.Unlock()
+
+	var data bytes.Buffer
+	for _, line := range lines {
+		data.Write(line)
+		data.WriteByte('\n')
+	}
+	if err := s.w.WriteBatch(ctx, s.batchKey(first), data.Bytes()); err != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// Put the records back before the ones logged meanwhile.
+		s.lines = append(lines, s.lines...)
+		s.first = first
+		if over := len(s.lines) - s.cfg.MaxBuffered; over > 0 {
+			log.Printf("dropped %d audit records after failed writes", over)
+			s.lines = slices.Clone(s.lines[over:])
+		}
+		return err
+	}
+	return nil
+}
+
+// batchKey returns a key such as "<prefix>2026/10/19/20261019T103000Z-<random>.ndjson",
+// so that batches are listed in chronological order.
+func (s *AuditSink) batchKey(t time.Time) string {
+	t = t.UTC()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return s.cfg.Prefix + t.Format("2006/01/02/20060102T150405Z") + "-" + hex.EncodeToString(suffix) + ".ndjson"
+}
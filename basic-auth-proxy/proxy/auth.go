@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrUnauthorized is returned when the request has no valid credentials.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrUserNotFound is returned by a Store when the user does not exist.
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// User is a user allowed to access upstreams through the proxy.
+type User struct {
+	Name string
+	// PasswordHash is a bcrypt hash, or an argon2 hash in the PHC string format.
+	PasswordHash string
+	// Upstreams lists the upstream URL prefixes the user may access.
+	// The scheme may be omitted to allow both http and https.
+	// An empty list allows all upstreams.
+	Upstreams []string
+}
+
+// AllowsUpstream reports whether u may access the upstream URL.
+func (u *User) AllowsUpstream(upstream *url.URL) bool {
+	if len(u.Upstreams) == 0 {
+		return true
+	}
+	for _, allowed := range u.Upstreams {
+		if !strings.Contains(allowed, "://") {
+			// Allow host-only entries such as "api.example.com/v1".
+			allowed = "//" + allowed
+		}
+		a, err := url.Parse(allowed)
+		if err != nil {
+			continue
+		}
+		if a.Scheme != "" && !strings.EqualFold(a.Scheme, upstream.Scheme) {
+			continue
+		}
+		if !strings.EqualFold(a.Host, upstream.Host) {
+			continue
+		}
+		prefix := strings.TrimSuffix(a.Path, "/")
+		if prefix == "" || upstream.Path == prefix || strings.HasPrefix(upstream.Path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Store looks up users by name.
+type Store interface {
+	// Lookup returns ErrUserNotFound if the user does not exist.
+	Lookup(ctx context.Context, name string) (*User, error)
+}
+
+// MapStore is an in-memory Store.
+type MapStore map[string]*User
+
+var _ Store = MapStore(nil)
+
+func (s MapStore) Lookup(_ context.Context, name string) (*User, error) {
+	u, ok := s[name]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+// dummyHash is compared against when a user is not found,
+// so that unknown and known users take the same time to reject.
+// It is a bcrypt hash of "dummy password" with bcrypt.DefaultCost, precomputed to keep cold starts fast.
+var dummyHash = []byte("$2a$10$BOkpQSQcWOXjBCIblb.xxOrW3UbkzrKZUkd/l8StjfWU7ENrXmnYG")
+
+// Authenticate checks the Basic auth credentials of req against store.
+// It returns ErrUnauthorized if the credentials are missing or invalid.
+func Authenticate(ctx context.Context, store Store, req *http.Request) (*User, error) {
+	name, password, ok := req.BasicAuth()
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	user, err := store.Lookup(ctx, name)
+	if errors.Is(err, ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if err := VerifyPassword(user.PasswordHash, password); err != nil {
+		return nil, ErrUnauthorized
+	}
+	return user, nil
+}
+
+// VerifyPassword compares password with a bcrypt or argon2 hash in constant time.
+func VerifyPassword(hash, password string) error {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	case strings.HasPrefix(hash, "$argon2id$"), strings.HasPrefix(hash, "$argon2i$"):
+		return verifyArgon2(hash, password)
+	}
+	return errors.New("unsupported password hash")
+}
+
+// Upper bounds of the argon2 parameters of stored hashes,
+// so that a bad entry cannot exhaust the memory or CPU time of the isolate.
+const (
+	maxArgon2Memory  = 64 * 1024 // KiB
+	maxArgon2Time    = 10
+	maxArgon2Threads = 16
+	maxArgon2KeyLen  = 64
+)
+
+// verifyArgon2 verifies a PHC formatted hash such as
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>.
+func verifyArgon2(hash, password string) error {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return errors.New("invalid argon2 hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return errors.New("unsupported argon2 version")
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+	if threads < 1 || threads > maxArgon2Threads || time < 1 || time > maxArgon2Time ||
+		memory < 8*uint32(threads) || memory > maxArgon2Memory {
+		return fmt.Errorf("argon2 parameters out of range: m=%d,t=%d,p=%d", memory, time, threads)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return fmt.Errorf("invalid argon2 hash: %w", err)
+	}
+	if len(want) < 4 || len(want) > maxArgon2KeyLen {
+		return fmt.Errorf("argon2 hash length out of range: %d", len(want))
+	}
+	var got []byte
+	if parts[1] == "argon2id" {
+		got = argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(want)))
+	} else {
+		got = argon2.Key([]byte(password), salt, time, memory, threads, uint32(len(want)))
+	}
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return errors.New("password mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func bcryptHash(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(hash)
+}
+
+func argon2Hash(password string) string {
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte(password), salt, 1, 64, 1, 32)
+	return fmt.Sprintf("$argon2id$v=%d$m=64,t=1,p=1$%s$%s", argon2.Version,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+type errStore struct{}
+
+func (errStore) Lookup(context.Context, string) (*User, error) {
+	return nil, errors.New("kv unavailable")
+}
+
+func TestAuthenticate(t *testing.T) {
+	htpasswd := fmt.Sprintf(`
+# comment
+alice:%s
+bob:%s https://a.example.com/api
+`, bcryptHash(t, "alice-pass"), argon2Hash("bob-pass"))
+	store, err := ParseHtpasswd(strings.NewReader(htpasswd))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		store    Store
+		user     string
+		password string
+		noAuth   bool
+		want     string
+		wantErr  error
+	}{
+		"bcrypt":         {store: store, user: "alice", password: "alice-pass", want: "alice"},
+		"argon2id":       {store: store, user: "bob", password: "bob-pass", want: "bob"},
+		"wrong password": {store: store, user: "alice", password: "bob-pass", wantErr: ErrUnauthorized},
+		"unknown user":   {store: store, user: "carol", password: "alice-pass", wantErr: ErrUnauthorized},
+		"empty password": {store: store, user: "bob", password: "", wantErr: ErrUnauthorized},
+		"no credentials": {store: store, noAuth: true, wantErr: ErrUnauthorized},
+		"store error":    {store: errStore{}, user: "alice", password: "alice-pass"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "https://proxy.example.com/", nil)
+			if !tt.noAuth {
+				req.SetBasicAuth(tt.user, tt.password)
+			}
+			user, err := Authenticate(context.Background(), tt.store, req)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("want error, got user %q", user.Name)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("want %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.Name != tt.want {
+				t.Errorf("got user %q, want %q", user.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	for _, hash := range []string{
+		"plain-text",
+		"{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+		"$argon2id$v=19$m=64,t=1,p=1$bad",
+		"$argon2id$v=16$m=64,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg$AAAA",
+		// Parameters out of range must be rejected before hashing.
+		"$argon2id$v=19$m=4194304,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg$AAAAAAAAAAAAAAAAAAAAAA",
+		"$argon2id$v=19$m=64,t=1000,p=1$MDEyMzQ1Njc4OWFiY2RlZg$AAAAAAAAAAAAAAAAAAAAAA",
+		"$argon2id$v=19$m=64,t=1,p=255$MDEyMzQ1Njc4OWFiY2RlZg$AAAAAAAAAAAAAAAAAAAAAA",
+		"$argon2id$v=19$m=64,t=1,p=0$MDEyMzQ1Njc4OWFiY2RlZg$AAAAAAAAAAAAAAAAAAAAAA",
+		"$argon2id$v=19$m=64,t=0,p=1$MDEyMzQ1Njc4OWFiY2RlZg$AAAAAAAAAAAAAAAAAAAAAA",
+		"$argon2id$v=19$m=64,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg$" + strings.Repeat("A", 200),
+	} {
+		if err := VerifyPassword(hash, "password"); err == nil {
+			t.Errorf("%s: want error, got nil", hash)
+		}
+	}
+}
+
+func TestDummyHash(t *testing.T) {
+	if err := bcrypt.CompareHashAndPassword(dummyHash, []byte("dummy password")); err != nil {
+		t.Fatal(err)
+	}
+	if cost, err := bcrypt.Cost(dummyHash); err != nil || cost != bcrypt.DefaultCost {
+		t.Errorf("cost %d, %v; want %d to take as long as real hashes", cost, err, bcrypt.DefaultCost)
+	}
+}
+
+func TestAllowsUpstream(t *testing.T) {
+	user := &User{Upstreams: []string{"https://a.example.com/api", "b.example.com"}}
+	tests := map[string]bool{
+		"https://a.example.com/api":       true,
+		"https://a.example.com/api/users": true,
+		"https://a.example.com/apikeys":   false,
+		"http://a.example.com/api":        false,
+		"https://a.example.com/":          false,
+		"http://b.example.com/anything":   true,
+		"https://c.example.com/api":       false,
+	}
+	for rawURL, want := range tests {
+		u, _ := url.Parse(rawURL)
+		if got := user.AllowsUpstream(u); got != want {
+			t.Errorf("%s: got %v, want %v", rawURL, got, want)
+		}
+	}
+	u, _ := url.Parse("https://any.example.com/")
+	if !(&User{}).AllowsUpstream(u) {
+		t.Error("empty allowlist must allow all upstreams")
+	}
+}
+
+func TestParseHtpasswd(t *testing.T) {
+	for _, in := range []string{"nohash", ":hash", "alice:"} {
+		if _, err := ParseHtpasswd(strings.NewReader(in)); err == nil {
+			t.Errorf("%q: want error, got nil", in)
+		}
+	}
+}
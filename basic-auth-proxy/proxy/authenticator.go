@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"errors"
+	"log"
+	"net/http"
+)
+
+// Authenticator authenticates requests to the proxy.
+type Authenticator interface {
+	// Authenticate returns the user sending req.
+	// If ok is false, Authenticate has written the response, such as
+	// a challenge, a login redirect or an error, and req must not be proxied.
+	Authenticate(w http.ResponseWriter, req *http.Request) (user *User, ok bool)
+}
+
+// BasicAuth is an Authenticator for Basic authentication.
+type BasicAuth struct {
+	Store Store
+	// Realm is the realm sent in the WWW-Authenticate header.
+	Realm string
+}
+
+var _ Authenticator = (*BasicAuth)(nil)
+
+func (a *BasicAuth) Authenticate(w http.ResponseWriter, req *http.Request) (*User, bool) {
+	user, err := Authenticate(req.Context(), a.Store, req)
+	if errors.Is(err, ErrUnauthorized) {
+		realm := a.Realm
+		if realm == "" {
+			realm = "login is required"
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("failed to authenticate: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseHtpasswd parses users in the htpasswd format.
+// Each line is "name:hash", optionally followed by whitespace separated
+// upstream URL prefixes the user is allowed to access:
+//
+//	alice:$2y$10$...
+//	bob:$argon2id$v=19$m=65536,t=3,p=4$... https://a.example.com https://b.example.com/api
+//
+// Empty lines and lines starting with '#' are ignored.
+func ParseHtpasswd(r io.Reader) (MapStore, error) {
+	store := MapStore{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, err := ParseHtpasswdEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		store[user.Name] = user
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// ParseHtpasswdEntry parses a single "name:hash [upstream...]" line.
+func ParseHtpasswdEntry(line string) (*User, error) {
+	name, rest, ok := strings.Cut(strings.TrimSpace(line), ":")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid htpasswd entry")
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("missing password hash for %q", name)
+	}
+	return &User{
+		Name:         name,
+		PasswordHash: fields[0],
+		Upstreams:    fields[1:],
+	}, nil
+}
@@ -0,0 +1,378 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sessionCookieName = "proxy_session"
+	stateCookieName   = "proxy_oidc_state"
+	stateTTL          = 10 * time.Minute
+)
+
+// OIDCConfig configures OIDC authentication.
+type OIDCConfig struct {
+	// Issuer is the issuer URL of the IdP.
+	Issuer string
+	// AuthURL, TokenURL and JWKSURL are the IdP endpoints.
+	// If any of them is empty, they are discovered from
+	// Issuer + "/.well-known/openid-configuration".
+	AuthURL  string
+	TokenURL string
+	JWKSURL  string
+
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback URL registered at the IdP.
+	// Its path is served by the proxy and is not forwarded to the upstream.
+	RedirectURL string
+	// Scopes requested in addition to "openid". Defaults to "email" and "profile".
+	Scopes []string
+
+	// GroupsClaim is the ID token claim listing the user's groups. Defaults to "groups".
+	GroupsClaim string
+	// AllowedGroups and AllowedEmailDomains restrict the users allowed to log in.
+	// A user matching either list is allowed. If both are empty, any user is allowed.
+	AllowedGroups       []string
+	AllowedEmailDomains []string
+
+	// SessionKey signs the session cookies. It must be at least 32 bytes.
+	SessionKey []byte
+	// SessionTTL is the lifetime of a session. Defaults to 12 hours.
+	SessionTTL time.Duration
+
+	// HTTPClient sends requests to the IdP. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+func (c *OIDCConfig) groupsClaim() string {
+	if c.GroupsClaim == "" {
+		return "groups"
+	}
+	return c.GroupsClaim
+}
+
+// OIDC is an Authenticator for the OIDC authorization code flow with PKCE.
+// Unauthenticated GET requests are redirected to the IdP, and a session
+// cookie is set when the IdP redirects back to RedirectURL.
+type OIDC struct {
+	cfg          OIDCConfig
+	callbackPath string
+	client       *http.Client
+	signer       *signer
+	now          func() time.Time
+
+	discoverMu sync.Mutex
+	keys       *keySet
+}
+
+var _ Authenticator = (*OIDC)(nil)
+
+// session is the value of the session cookie.
+type session struct {
+	Name string `json:"name"`
+}
+
+// loginState is the value of the state cookie set during login.
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+}
+
+// NewOIDC returns an OIDC Authenticator for cfg.
+func NewOIDC(cfg OIDCConfig) (*OIDC, error) {
+	switch {
+	case cfg.Issuer == "":
+		return nil, errors.New("proxy: OIDC issuer must be set")
+	case cfg.ClientID == "":
+		return nil, errors.New("proxy: OIDC client ID must be set")
+	case len(cfg.SessionKey) < 32:
+		return nil, errors.New("proxy: OIDC session key must be at least 32 bytes")
+	}
+	redirectURL, err := url.Parse(cfg.RedirectURL)
+	if err != nil || !redirectURL.IsAbs() {
+		return nil, errors.New("proxy: OIDC redirect URL must be an absolute URL")
+	}
+	if cfg.Scopes == nil {
+		cfg.Scopes = []string{"email", "profile"}
+	}
+	if cfg.SessionTTL == 0 {
+		cfg.SessionTTL = 12 * time.Hour
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	a := &OIDC{
+		cfg:          cfg,
+		callbackPath: redirectURL.Path,
+		client:       client,
+		now:          time.Now,
+	}
+	a.signer = &signer{key: cfg.SessionKey, now: func() time.Time { return a.now() }}
+	return a, nil
+}
+
+func (a *OIDC) Authenticate(w http.ResponseWriter, req *http.Request) (*User, bool) {
+	if req.URL.Path == a.callbackPath {
+		a.handleCallback(w, req)
+		return nil, false
+	}
+	if c, err := req.Cookie(sessionCookieName); err == nil {
+		var s session
+		if err := a.signer.verify(purposeSession, c.Value, &s); err == nil && s.Name != "" {
+			removeCookie(req, sessionCookieName)
+			removeCookie(req, stateCookieName)
+			return &User{Name: s.Name}, true
+		}
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	a.redirectToLogin(w, req)
+	return nil, false
+}
+
+// redirectToLogin starts the authorization code flow.
+func (a *OIDC) redirectToLogin(w http.ResponseWriter, req *http.Request) {
+	if err := a.discover(req); err != nil {
+		log.Printf("failed to discover OIDC endpoints: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	st := loginState{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: randomString(),
+		ReturnTo: req.URL.RequestURI(),
+	}
+	token, err := a.signer.sign(purposeLoginState, st, stateTTL)
+	if err != nil {
+		log.Printf("failed to sign OIDC state: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, newCookie(stateCookieName, token, stateTTL))
+
+	challenge := sha256.Sum256([]byte(st.Verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {a.cfg.ClientID},
+		"redirect_uri":          {a.cfg.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, a.cfg.Scopes...), " ")},
+		"state":                 {st.State},
+		"nonce":                 {st.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	authURL := a.cfg.AuthURL
+	if strings.Contains(authURL, "?") {
+		authURL += "&" + q.Encode()
+	} else {
+		authURL += "?" + q.Encode()
+	}
+	http.Redirect(w, req, authURL, http.StatusFound)
+}
+
+// handleCallback completes the authorization code flow and sets the session cookie.
+func (a *OIDC) handleCallback(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "Login failed: "+e, http.StatusUnauthorized)
+		return
+	}
+	c, err := req.Cookie(stateCookieName)
+	if err != nil {
+		http.Error(w, "Login session not found", http.StatusBadRequest)
+		return
+	}
+	var st loginState
+	if err := a.signer.verify(purposeLoginState, c.Value, &st); err != nil {
+		http.Error(w, "Login session expired", http.StatusBadRequest)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(st.State)) != 1 {
+		http.Error(w, "Login state mismatch", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, newCookie(stateCookieName, "", -1))
+
+	if err := a.discover(req); err != nil {
+		log.Printf("failed to discover OIDC endpoints: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	idToken, err := a.exchange(req, q.Get("code"), st.Verifier)
+	if err != nil {
+		log.Printf("failed to exchange OIDC code: %v", err)
+		http.Error(w, "Login failed", http.StatusBadGateway)
+		return
+	}
+	claims, err := verifyIDToken(req.Context(), a.keys, idToken, &a.cfg, st.Nonce, a.now())
+	if err != nil {
+		log.Printf("failed to verify ID token: %v", err)
+		http.Error(w, "Login failed", http.StatusUnauthorized)
+		return
+	}
+	if !a.allowed(claims) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	name := claims.Email
+	if name == "" {
+		name = claims.Subject
+	}
+	token, err := a.signer.sign(purposeSession, session{Name: name}, a.cfg.SessionTTL)
+	if err != nil {
+		log.Printf("failed to sign session: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, newCookie(sessionCookieName, token, a.cfg.SessionTTL))
+	http.Redirect(w, req, safeReturnTo(st.ReturnTo), http.StatusFound)
+}
+
+// exchange redeems code at the token endpoint and returns the ID token.
+func (a *OIDC) exchange(req *http.Request, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {a.cfg.RedirectURL},
+		"client_id":     {a.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	if a.cfg.ClientSecret != "" {
+		form.Set("client_secret", a.cfg.ClientSecret)
+	}
+	tokenReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, a.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tokenReq.Header.Set("Accept", "application/json")
+	resp, err := a.client.Do(tokenReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+	var body struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return body.IDToken, nil
+}
+
+// discover fills the IdP endpoints from the discovery document if not configured.
+func (a *OIDC) discover(req *http.Request) error {
+	a.discoverMu.Lock()
+	defer a.discoverMu.Unlock()
+	if a.keys != nil {
+		return nil
+	}
+	if a.cfg.AuthURL == "" || a.cfg.TokenURL == "" || a.cfg.JWKSURL == "" {
+		var doc struct {
+			Issuer   string `json:"issuer"`
+			AuthURL  string `json:"authorization_endpoint"`
+			TokenURL string `json:"token_endpoint"`
+			JWKSURL  string `json:"jwks_uri"`
+		}
+		wellKnown := strings.TrimSuffix(a.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := getJSON(req.Context(), a.client, wellKnown, &doc); err != nil {
+			return err
+		}
+		if doc.Issuer != a.cfg.Issuer {
+			return fmt.Errorf("discovered issuer %q does not match %q", doc.Issuer, a.cfg.Issuer)
+		}
+		a.cfg.AuthURL, a.cfg.TokenURL, a.cfg.JWKSURL = doc.AuthURL, doc.TokenURL, doc.JWKSURL
+	}
+	a.keys = &keySet{url: a.cfg.JWKSURL, client: a.client, now: a.now}
+	return nil
+}
+
+// allowed reports whether the user of claims matches the allow rules.
+func (a *OIDC) allowed(claims *idTokenClaims) bool {
+	if len(a.cfg.AllowedGroups) == 0 && len(a.cfg.AllowedEmailDomains) == 0 {
+		return true
+	}
+	for _, g := range claims.Groups {
+		for _, allowed := range a.cfg.AllowedGroups {
+			if g == allowed {
+				return true
+			}
+		}
+	}
+	// An unverified email address could be chosen by the user,
+	// and an IdP omitting email_verified gives no guarantee either.
+	if claims.Email != "" && claims.EmailVerified != nil && *claims.EmailVerified {
+		_, domain, _ := strings.Cut(claims.Email, "@")
+		for _, allowed := range a.cfg.AllowedEmailDomains {
+			if strings.EqualFold(domain, allowed) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newCookie(name, value string, ttl time.Duration) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// removeCookie removes the named cookie from req so it is not forwarded to the upstream.
+func removeCookie(req *http.Request, name string) {
+	cookies := req.Cookies()
+	req.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != name {
+			req.AddCookie(c)
+		}
+	}
+}
+
+// safeReturnTo returns path if it is a local path, and "/" otherwise,
+// so that the login flow cannot redirect to another site.
+func safeReturnTo(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,396 @@
+package proxy
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeIdP is an OIDC provider issuing RS256 ID tokens.
+type fakeIdP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	// claims are added to or override the claims of issued ID tokens.
+	claims map[string]any
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+}
+
+type authRequest struct {
+	challenge string
+	nonce     string
+}
+
+var testRSAKey = func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	idp := &fakeIdP{key: testRSAKey, codes: map[string]authRequest{}, claims: map[string]any{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		if q.Get("client_id") != "client" || q.Get("code_challenge_method") != "S256" || q.Get("response_type") != "code" {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		code := randomString()
+		idp.mu.Lock()
+		idp.codes[code] = authRequest{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+		idp.mu.Unlock()
+		http.Redirect(w, req, q.Get("redirect_uri")+"?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		idp.mu.Lock()
+		ar, ok := idp.codes[req.FormValue("code")]
+		delete(idp.codes, req.FormValue("code"))
+		idp.mu.Unlock()
+		challenge := sha256.Sum256([]byte(req.FormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != ar.challenge || req.FormValue("client_secret") != "secret" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		claims := map[string]any{
+			"iss":   idp.URL,
+			"sub":   "user-1",
+			"aud":   "client",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": ar.nonce,
+			"email": "alice@example.com",
+
+			"email_verified": true,
+		}
+		for k, v := range idp.claims {
+			claims[k] = v
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.sign(t, claims)})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func (idp *fakeIdP) sign(t *testing.T, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// newOIDCProxy returns a proxy using idp, and the requests its upstream received.
+func newOIDCProxy(t *testing.T, idp *fakeIdP, modify func(*OIDCConfig)) (*Proxy, <-chan *http.Request) {
+	t.Helper()
+	cfg := OIDCConfig{
+		Issuer:       idp.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "https://proxy.example.com/oauth2/callback",
+		SessionKey:   []byte(strings.Repeat("k", 32)),
+		HTTPClient:   idp.Client(),
+	}
+	if modify != nil {
+		modify(&cfg)
+	}
+	auth, err := NewOIDC(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan *http.Request, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received <- req
+	}))
+	t.Cleanup(upstream.Close)
+	u, _ := url.Parse(upstream.URL)
+	p, err := New(Config{Upstream: u, Auth: auth, Transport: upstream.Client().Transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, received
+}
+
+// login runs the authorization code flow through the proxy and the IdP,
+// and returns the callback response.
+func login(t *testing.T, p *Proxy, idp *fakeIdP, target string) *http.Response {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login: got status %d, want %d", rec.Code, http.StatusFound)
+	}
+	stateCookies := rec.Result().Cookies()
+
+	client := idp.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: got status %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+
+	callback := httptest.NewRequest("GET", resp.Header.Get("Location"), nil)
+	for _, c := range stateCookies {
+		callback.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, callback)
+	return rec.Result()
+}
+
+func findCookie(resp *http.Response, name string) *http.Cookie {
+	for _, c := range resp.Cookies() {
+		if c.Name == name && c.MaxAge > 0 {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newFakeIdP(t)
+	p, received := newOIDCProxy(t, idp, func(cfg *OIDCConfig) {
+		cfg.AllowedEmailDomains = []string{"example.com"}
+	})
+	resp := login(t, p, idp, "https://proxy.example.com/private?x=1")
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/private?x=1" {
+		t.Fatalf("callback: got %d to %q, want redirect to /private?x=1", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	sess := findCookie(resp, sessionCookieName)
+	if sess == nil || !sess.HttpOnly || !sess.Secure {
+		t.Fatalf("want a secure session cookie, got %v", sess)
+	}
+
+	req := httptest.NewRequest("GET", "https://proxy.example.com/private?x=1", nil)
+	req.AddCookie(sess)
+	req.AddCookie(&http.Cookie{Name: stateCookieName, Value: "left over from another login"})
+	req.AddCookie(&http.Cookie{Name: "app", Value: "kept"})
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	got := <-received
+	if _, err := got.Cookie(sessionCookieName); err == nil {
+		t.Error("session cookie must not be forwarded to the upstream")
+	}
+	if _, err := got.Cookie(stateCookieName); err == nil {
+		t.Error("state cookie must not be forwarded to the upstream")
+	}
+	if c, err := got.Cookie("app"); err != nil || c.Value != "kept" {
+		t.Errorf("other cookies must be forwarded, got %v", got.Header.Get("Cookie"))
+	}
+}
+
+func TestOIDCLoginRejected(t *testing.T) {
+	tests := map[string]struct {
+		claims map[string]any
+		modify func(*OIDCConfig)
+		want   int
+	}{
+		"email domain not allowed": {
+			modify: func(cfg *OIDCConfig) { cfg.AllowedEmailDomains = []string{"corp.example.com"} },
+			want:   http.StatusForbidden,
+		},
+		"unverified email": {
+			claims: map[string]any{"email_verified": false},
+			modify: func(cfg *OIDCConfig) { cfg.AllowedEmailDomains = []string{"example.com"} },
+			want:   http.StatusForbidden,
+		},
+		"email_verified missing": {
+			claims: map[string]any{"email_verified": nil},
+			modify: func(cfg *OIDCConfig) { cfg.AllowedEmailDomains = []string{"example.com"} },
+			want:   http.StatusForbidden,
+		},
+		"group allowed": {
+			claims: map[string]any{"groups": []string{"dev", "admin"}},
+			modify: func(cfg *OIDCConfig) { cfg.AllowedGroups = []string{"admin"} },
+			want:   http.StatusFound,
+		},
+		"custom groups claim": {
+			claims: map[string]any{"roles": []string{"admin"}},
+			modify: func(cfg *OIDCConfig) { cfg.GroupsClaim, cfg.AllowedGroups = "roles", []string{"admin"} },
+			want:   http.StatusFound,
+		},
+		"group not allowed": {
+			claims: map[string]any{"groups": []string{"dev"}},
+			modify: func(cfg *OIDCConfig) { cfg.AllowedGroups = []string{"admin"} },
+			want:   http.StatusForbidden,
+		},
+		"expired token":  {claims: map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}, want: http.StatusUnauthorized},
+		"wrong audience": {claims: map[string]any{"aud": []string{"other"}}, want: http.StatusUnauthorized},
+		"wrong issuer":   {claims: map[string]any{"iss": "https://evil.example.com"}, want: http.StatusUnauthorized},
+		"wrong nonce":    {claims: map[string]any{"nonce": "replayed"}, want: http.StatusUnauthorized},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			idp := newFakeIdP(t)
+			for k, v := range tt.claims {
+				idp.claims[k] = v
+			}
+			p, _ := newOIDCProxy(t, idp, tt.modify)
+			resp := login(t, p, idp, "https://proxy.example.com/")
+			if resp.StatusCode != tt.want {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tt.want)
+			}
+			if tt.want != http.StatusFound && findCookie(resp, sessionCookieName) != nil {
+				t.Error("session cookie must not be set")
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackState(t *testing.T) {
+	idp := newFakeIdP(t)
+	p, _ := newOIDCProxy(t, idp, nil)
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "https://proxy.example.com/oauth2/callback?code=x&state=y", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("no state cookie: got status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "https://proxy.example.com/", nil))
+	req := httptest.NewRequest("GET", "https://proxy.example.com/oauth2/callback?code=x&state=forged", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("forged state: got status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestOIDCUnauthenticated(t *testing.T) {
+	idp := newFakeIdP(t)
+	p, received := newOIDCProxy(t, idp, nil)
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("POST", "https://proxy.example.com/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("POST: got status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest("GET", "https://proxy.example.com/", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "forged.session"})
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	loc, _ := url.Parse(rec.Header().Get("Location"))
+	if rec.Code != http.StatusFound || !strings.HasPrefix(loc.String(), idp.URL+"/authorize") {
+		t.Errorf("forged session: got %d to %q, want redirect to the IdP", rec.Code, loc)
+	}
+	if q := loc.Query(); q.Get("code_challenge") == "" || q.Get("scope") != "openid email profile" {
+		t.Errorf("unexpected authorization request %q", loc.RawQuery)
+	}
+	if len(received) != 0 {
+		t.Error("request must not reach the upstream")
+	}
+}
+
+// TestOIDCStateAsSession checks that the state cookie of a login redirect,
+// which any visitor can get, is not accepted as a session.
+func TestOIDCStateAsSession(t *testing.T) {
+	idp := newFakeIdP(t)
+	p, received := newOIDCProxy(t, idp, func(cfg *OIDCConfig) {
+		cfg.AllowedGroups = []string{"admin"}
+	})
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "https://proxy.example.com/", nil))
+	var state *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == stateCookieName {
+			state = c
+		}
+	}
+	if state == nil {
+		t.Fatal("no state cookie")
+	}
+
+	req := httptest.NewRequest("GET", "https://proxy.example.com/private", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: state.Value})
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound || !strings.HasPrefix(rec.Header().Get("Location"), idp.URL+"/authorize") {
+		t.Errorf("got %d to %q, want redirect to the IdP", rec.Code, rec.Header().Get("Location"))
+	}
+	if len(received) != 0 {
+		t.Error("request must not reach the upstream")
+	}
+}
+
+func TestSignerRejectsEmptySession(t *testing.T) {
+	a, err := NewOIDC(OIDCConfig{
+		Issuer:      "https://idp.example.com",
+		ClientID:    "client",
+		RedirectURL: "https://proxy.example.com/oauth2/callback",
+		SessionKey:  []byte(strings.Repeat("k", 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := a.signer.sign(purposeSession, session{}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "https://proxy.example.com/", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+	if user, ok := a.Authenticate(httptest.NewRecorder(), req); ok {
+		t.Errorf("session without a name authenticated as %+v", user)
+	}
+}
+
+func TestSafeReturnTo(t *testing.T) {
+	for in, want := range map[string]string{
+		"/path?q=1":            "/path?q=1",
+		"//evil.example.com":   "/",
+		"/\\evil.example.com":  "/",
+		"https://evil.example": "/",
+		"":                     "/",
+	} {
+		if got := safeReturnTo(in); got != want {
+			t.Errorf("safeReturnTo(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package proxy implements a reverse proxy requiring Basic or OIDC authentication.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+)
+
+// Config configures a Proxy.
+type Config struct {
+	// Upstream is the URL requests are forwarded to.
+	// The request path is appended to the upstream path.
+	// It is ignored if Routes is set.
+	Upstream *url.URL
+	// Routes route requests to upstreams by host and path.
+	Routes []Route
+	// Auth authenticates requests. If nil, Basic authentication
+	// with Store and Realm is used.
+	Auth Authenticator
+	// Store looks up users for Basic authentication.
+	Store Store
+	// Transport sends requests to the upstream.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+	// Realm is the realm sent in the WWW-Authenticate header.
+	Realm string
+	// RateLimit limits requests and failed logins per client IP and username.
+	// If nil, requests are not limited.
+	RateLimit *RateLimitConfig
+	// AccessLog receives a record for each request.
+	// If nil, records are written to slog.Default().
+	AccessLog AccessLogger
+	// RedactedParams are the query parameters whose values are redacted
+	// in access records. If nil, DefaultRedactedParams is used.
+	RedactedParams []string
+}
+
+// Proxy is an http.Handler forwarding authenticated requests to upstreams.
+type Proxy struct {
+	routes         []*route
+	auth           Authenticator
+	accessLog      AccessLogger
+	redactedParams []string
+	rp             *httputil.ReverseProxy
+	now            func() time.Time
+}
+
+var _ http.Handler = (*Proxy)(nil)
+
+// New returns a Proxy for cfg.
+func New(cfg Config) (*Proxy, error) {
+	routes := cfg.Routes
+	if routes == nil {
+		if cfg.Upstream == nil || cfg.Upstream.Scheme == "" || cfg.Upstream.Host == "" {
+			return nil, errors.New("proxy: upstream must be an absolute URL")
+		}
+		routes = []Route{{Upstreams: []string{cfg.Upstream.String()}}}
+	}
+	var compiled []*route
+	needsAuth := false
+	for i, r := range routes {
+		rt, err := newRoute(r)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: route %d: %w", i, err)
+		}
+		compiled = append(compiled, rt)
+		needsAuth = needsAuth || !rt.public
+	}
+	auth := cfg.Auth
+	if auth == nil && needsAuth {
+		if cfg.Store == nil {
+			return nil, errors.New("proxy: either auth or store must be set")
+		}
+		auth = &BasicAuth{Store: cfg.Store, Realm: cfg.Realm}
+	}
+	if cfg.RateLimit != nil && auth != nil {
+		auth = newRateLimiter(auth, *cfg.RateLimit)
+	}
+	p := &Proxy{
+		routes:         compiled,
+		auth:           auth,
+		accessLog:      cfg.AccessLog,
+		redactedParams: cfg.RedactedParams,
+		now:            time.Now,
+	}
+	if p.accessLog == nil {
+		p.accessLog = SlogAccessLogger(nil)
+	}
+	if p.redactedParams == nil {
+		p.redactedParams = DefaultRedactedParams
+	}
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	p.rp = &httputil.ReverseProxy{
+		Rewrite:        p.rewrite,
+		Transport:      &failoverTransport{base: transport, now: p.now},
+		ModifyResponse: recordUpstreamStatus,
+		ErrorHandler:   handleUpstreamError,
+	}
+	return p, nil
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	start := p.now()
+	st := &requestState{}
+	req = req.WithContext(context.WithValue(req.Context(), requestStateKey{}, st))
+	st.in = req
+	rw := &recordingWriter{ResponseWriter: w}
+	defer func() {
+		p.accessLog.LogAccess(req.Context(), AccessRecord{
+			Time:           start,
+			User:           st.user,
+			ClientIP:       ClientIP(req),
+			Method:         req.Method,
+			Path:           req.URL.Path,
+			Query:          RedactQuery(req.URL.RawQuery, p.redactedParams),
+			Status:         rw.status,
+			UpstreamStatus: st.upstreamStatus,
+			Latency:        p.now().Sub(start),
+			Bytes:          rw.bytes,
+		})
+	}()
+	p.serve(rw, req, st)
+}
+
+func (p *Proxy) serve(w http.ResponseWriter, req *http.Request, st *requestState) {
+	rt := match(p.routes, req)
+	if rt == nil {
+		http.NotFound(w, req)
+		return
+	}
+	st.route = rt
+	st.forwardPath = rt.forwardPath(req.URL.Path)
+	st.candidates = rt.candidates(p.now())
+	if !rt.public {
+		user, ok := p.auth.Authenticate(w, req)
+		if !ok {
+			return
+		}
+		st.user = user.Name
+		// Only try the upstreams the user may access.
+		allowed := st.candidates[:0:0]
+		for _, u := range st.candidates {
+			if user.AllowsUpstream(forwardURL(u.url, req, st.forwardPath)) {
+				allowed = append(allowed, u)
+			}
+		}
+		if len(allowed) == 0 {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		st.candidates = allowed
+	}
+	p.rp.ServeHTTP(w, req)
+}
+
+// rewrite builds the upstream request. ReverseProxy has already removed
+// hop-by-hop headers and inbound X-Forwarded-* headers from pr.Out.
+func (p *Proxy) rewrite(pr *httputil.ProxyRequest) {
+	st := requestStateFrom(pr.In.Context())
+	pr.Out.URL = forwardURL(st.candidates[0].url, pr.In, st.forwardPath)
+	pr.Out.Host = ""
+	pr.SetXForwarded()
+	if ip := ClientIP(pr.In); ip != "" {
+		pr.Out.Header.Set("X-Forwarded-For", ip)
+	}
+	// Credentials for the proxy must not leak to the upstream.
+	pr.Out.Header.Del("Authorization")
+}
+
+// ClientIP returns the IP address of the client sending req.
+// On Workers, it is taken from the CF-Connecting-IP header.
+func ClientIP(req *http.Request) string {
+	if ip := req.Header.Get("CF-Connecting-IP"); ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func recordUpstreamStatus(resp *http.Response) error {
+	if st := requestStateFrom(resp.Request.Context()); st != nil {
+		st.upstreamStatus = resp.StatusCode
+	}
+	return nil
+}
+
+func handleUpstreamError(w http.ResponseWriter, req *http.Request, err error) {
+	log.Printf("failed to proxy %s %s: %v", req.Method, req.URL.Path, err)
+	http.Error(w, "Bad Gateway", http.StatusBadGateway)
+}
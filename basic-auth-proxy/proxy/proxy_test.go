@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newTestProxy returns a Proxy forwarding to a server running upstream,
+// and the requests the upstream received.
+func newTestProxy(t *testing.T, upstream http.HandlerFunc, upstreams ...string) (*Proxy, <-chan *http.Request) {
+	t.Helper()
+	received := make(chan *http.Request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received <- req
+		upstream(w, req)
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL + "/base")
+	p, err := New(Config{
+		Upstream:  u,
+		Store:     MapStore{"alice": {Name: "alice", PasswordHash: bcryptHash(t, "pass"), Upstreams: upstreams}},
+		Transport: srv.Client().Transport,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, received
+}
+
+func newAuthRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.SetBasicAuth("alice", "pass")
+	return req
+}
+
+func TestProxyForwardsRequest(t *testing.T) {
+	p, received := newTestProxy(t, func(w http.ResponseWriter, req *http.Request) {
+		io.Copy(io.Discard, req.Body)
+	})
+	req := newAuthRequest("POST", "https://proxy.example.com/users?id=1", strings.NewReader("body"))
+	req.Header.Set("CF-Connecting-IP", "203.0.113.7")
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	req.Header.Set("Connection", "X-Hop")
+	req.Header.Set("X-Hop", "1")
+	req.Header.Set("Keep-Alive", "timeout=5")
+	req.Header.Set("Proxy-Authorization", "Basic xxx")
+	req.Header.Set("X-Custom", "kept")
+	p.ServeHTTP(httptest.NewRecorder(), req)
+
+	got := <-received
+	if got.URL.Path != "/base/users" || got.URL.RawQuery != "id=1" {
+		t.Errorf("got URL %s, want /base/users?id=1", got.URL)
+	}
+	if got.Method != "POST" {
+		t.Errorf("got method %s, want POST", got.Method)
+	}
+	wantHeaders := map[string]string{
+		"Authorization":       "",
+		"Connection":          "",
+		"X-Hop":               "",
+		"Keep-Alive":          "",
+		"Proxy-Authorization": "",
+		"X-Custom":            "kept",
+		"X-Forwarded-For":     "203.0.113.7",
+		"X-Forwarded-Host":    "proxy.example.com",
+		"X-Forwarded-Proto":   "https",
+	}
+	for key, want := range wantHeaders {
+		if v := got.Header.Get(key); v != want {
+			t.Errorf("header %s: got %q, want %q", key, v, want)
+		}
+	}
+}
+
+func TestProxyPassesResponseThrough(t *testing.T) {
+	p, _ := newTestProxy(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Upstream", "yes")
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		w.Header().Set("Connection", "close")
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "short and stout")
+	})
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, newAuthRequest("GET", "https://proxy.example.com/", nil))
+
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusTeapot)
+	}
+	if got := rec.Header().Get("X-Upstream"); got != "yes" {
+		t.Errorf("X-Upstream: got %q, want yes", got)
+	}
+	if got := rec.Header().Values("Set-Cookie"); len(got) != 2 {
+		t.Errorf("Set-Cookie: got %q, want 2 values", got)
+	}
+	if got := rec.Header().Get("Connection"); got != "" {
+		t.Errorf("Connection: got %q, want it stripped", got)
+	}
+	if got := rec.Body.String(); got != "short and stout" {
+		t.Errorf("got body %q", got)
+	}
+}
+
+func TestProxyRejects(t *testing.T) {
+	tests := map[string]struct {
+		req       *http.Request
+		upstreams []string
+		want      int
+	}{
+		"no credentials": {
+			req:  httptest.NewRequest("GET", "https://proxy.example.com/", nil),
+			want: http.StatusUnauthorized,
+		},
+		"wrong password": {
+			req: func() *http.Request {
+				req := httptest.NewRequest("GET", "https://proxy.example.com/", nil)
+				req.SetBasicAuth("alice", "wrong")
+				return req
+			}(),
+			want: http.StatusUnauthorized,
+		},
+		"upstream not allowed": {
+			req:       newAuthRequest("GET", "https://proxy.example.com/", nil),
+			upstreams: []string{"https://other.example.com"},
+			want:      http.StatusForbidden,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, received := newTestProxy(t, func(http.ResponseWriter, *http.Request) {}, tt.upstreams...)
+			rec := httptest.NewRecorder()
+			p.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.want {
+				t.Errorf("got status %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("missing WWW-Authenticate header")
+			}
+			if len(received) != 0 {
+				t.Error("request must not reach the upstream")
+			}
+		})
+	}
+}
+
+func TestProxyUpstreamError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	u, _ := url.Parse(srv.URL)
+	srv.Close()
+	p, err := New(Config{
+		Upstream: u,
+		Store:    MapStore{"alice": {Name: "alice", PasswordHash: bcryptHash(t, "pass")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, newAuthRequest("GET", "https://proxy.example.com/", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+}
+
+func TestNew(t *testing.T) {
+	relative, _ := url.Parse("/path")
+	for name, cfg := range map[string]Config{
+		"no upstream":       {Store: MapStore{}},
+		"relative upstream": {Upstream: relative, Store: MapStore{}},
+		"no store":          {Upstream: &url.URL{Scheme: "https", Host: "example.com"}},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("%s: want error, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CounterState is the state of a rate limit counter.
+type CounterState struct {
+	// Count is the number of failures, or the remaining tokens of a token bucket.
+	Count float64 `json:"count"`
+	// Updated is when the counter was last updated.
+	Updated time.Time `json:"updated"`
+	// LockedUntil is when a lockout ends.
+	LockedUntil time.Time `json:"locked_until,omitempty"`
+}
+
+// CounterStore stores rate limit counters.
+// Counters are read and written without transactions, so concurrent requests
+// may occasionally be counted once; a store must only keep the latest state.
+type CounterStore interface {
+	// Load returns the counter for key, or a zero CounterState if there is none.
+	Load(ctx context.Context, key string) (CounterState, error)
+	// Store saves the counter for key. It may be discarded after ttl.
+	Store(ctx context.Context, key string, s CounterState, ttl time.Duration) error
+}
+
+// MemoryCounterStore is an in-memory CounterStore.
+// Expired counters are swept every sweepInterval stores, so the map stays
+// bounded by the keys seen within the longest TTL.
+type MemoryCounterStore struct {
+	mu       sync.Mutex
+	counters map[string]memoryCounter
+	stores   int
+	now      func() time.Time
+}
+
+// sweepInterval is the number of stores between sweeps of expired counters.
+const sweepInterval = 256
+
+type memoryCounter struct {
+	state   CounterState
+	expires time.Time
+}
+
+var _ CounterStore = (*MemoryCounterStore)(nil)
+
+// NewMemoryCounterStore returns an empty MemoryCounterStore.
+func NewMemoryCounterStore() *MemoryCounterStore {
+	return &MemoryCounterStore{counters: map[string]memoryCounter{}, now: time.Now}
+}
+
+func (s *MemoryCounterStore) Load(_ context.Context, key string) (CounterState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.counters[key]
+	if !ok || !s.now().Before(c.expires) {
+		delete(s.counters, key)
+		return CounterState{}, nil
+	}
+	return c.state, nil
+}
+
+func (s *MemoryCounterStore) Store(_ context.Context, key string, state CounterState, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.counters[key] = memoryCounter{state: state, expires: now.Add(ttl)}
+	if s.stores++; s.stores%sweepInterval == 0 {
+		for k, c := range s.counters {
+			if !now.Before(c.expires) {
+				delete(s.counters, k)
+			}
+		}
+	}
+	return nil
+}
+
+// RateLimitConfig configures request rate limiting and brute-force lockout.
+// Only requests to routes requiring authentication are limited;
+// public routes are passed through without counting.
+type RateLimitConfig struct {
+	// Store holds the failed login counters. If nil, a MemoryCounterStore is used.
+	Store CounterStore
+	// Buckets holds the token buckets of the request limit. If nil, a MemoryCounterStore is used.
+	// Buckets are written on every request, so a store with slow or
+	// eventually consistent writes such as KV is not suitable for them.
+	Buckets CounterStore
+
+	// Rate is the number of requests per second allowed from a client IP,
+	// with bursts of up to Burst requests. Zero disables the limit.
+	Rate  float64
+	Burst int
+
+	// MaxFailures is the number of failed logins of a client IP or a username
+	// after which further attempts are locked out. Zero disables lockout.
+	MaxFailures int
+	// Lockout is the first lockout duration, doubled for each further failure
+	// up to MaxLockout. Defaults to 1 minute and 1 hour.
+	Lockout    time.Duration
+	MaxLockout time.Duration
+	// FailureWindow is how long failures are remembered after the last one.
+	// Defaults to 15 minutes.
+	FailureWindow time.Duration
+}
+
+// rateLimiter is an Authenticator limiting the requests and failed logins of next.
+type rateLimiter struct {
+	next Authenticator
+	cfg  RateLimitConfig
+	now  func() time.Time
+}
+
+func newRateLimiter(next Authenticator, cfg RateLimitConfig) *rateLimiter {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryCounterStore()
+	}
+	if cfg.Buckets == nil {
+		cfg.Buckets = NewMemoryCounterStore()
+	}
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+	if cfg.Lockout == 0 {
+		cfg.Lockout = time.Minute
+	}
+	if cfg.MaxLockout == 0 {
+		cfg.MaxLockout = time.Hour
+	}
+	if cfg.FailureWindow == 0 {
+		cfg.FailureWindow = 15 * time.Minute
+	}
+	return &rateLimiter{next: next, cfg: cfg, now: time.Now}
+}
+
+func (l *rateLimiter) Authenticate(w http.ResponseWriter, req *http.Request) (*User, bool) {
+	ctx := req.Context()
+	ipKey := "ip:" + ClientIP(req)
+	if wait := l.take(ctx, "bucket:"+ipKey); wait > 0 {
+		tooManyRequests(w, wait)
+		return nil, false
+	}
+
+	keys := []string{"failures:" + ipKey}
+	name, _, hasCredentials := req.BasicAuth()
+	if hasCredentials {
+		keys = append(keys, "failures:user:"+name)
+	}
+	if wait := l.lockedOut(ctx, keys); wait > 0 {
+		tooManyRequests(w, wait)
+		return nil, false
+	}
+
+	rw := &recordingWriter{ResponseWriter: w}
+	user, ok := l.next.Authenticate(rw, req)
+	switch {
+	case ok:
+		// Only the username's failures are reset, so that an attacker cannot
+		// clear the failures of an IP by logging in to their own account.
+		if hasCredentials {
+			l.reset(ctx, "failures:user:"+name)
+		}
+	case rw.status == http.StatusUnauthorized && hasCredentials:
+		for _, key := range keys {
+			l.fail(ctx, key)
+		}
+	}
+	return user, ok
+}
+
+// take takes a token from the bucket for key, and returns how long to wait if it is empty.
+func (l *rateLimiter) take(ctx context.Context, key string) time.Duration {
+	if l.cfg.Rate <= 0 {
+		return 0
+	}
+	now := l.now()
+	burst := float64(l.cfg.Burst)
+	s, err := l.cfg.Buckets.Load(ctx, key)
+	if err != nil {
+		log.Printf("failed to load rate limit counter: %v", err)
+		return 0
+	}
+	tokens := burst
+	if !s.Updated.IsZero() {
+		tokens = math.Min(burst, s.Count+now.Sub(s.Updated).Seconds()*l.cfg.Rate)
+	}
+	if tokens < 1 {
+		return time.Duration((1 - tokens) / l.cfg.Rate * float64(time.Second))
+	}
+	refill := time.Duration(burst / l.cfg.Rate * float64(time.Second))
+	if err := l.cfg.Buckets.Store(ctx, key, CounterState{Count: tokens - 1, Updated: now}, refill); err != nil {
+		log.Printf("failed to store rate limit counter: %v", err)
+	}
+	return 0
+}
+
+// lockedOut returns the longest remaining lockout of keys.
+func (l *rateLimiter) lockedOut(ctx context.Context, keys []string) time.Duration {
+	if l.cfg.MaxFailures <= 0 {
+		return 0
+	}
+	var wait time.Duration
+	for _, key := range keys {
+		s, err := l.cfg.Store.Load(ctx, key)
+		if err != nil {
+			log.Printf("failed to load failure counter: %v", err)
+			continue
+		}
+		wait = max(wait, s.LockedUntil.Sub(l.now()))
+	}
+	return wait
+}
+
+// fail records a failed login for key, locking it out once MaxFailures is reached.
+func (l *rateLimiter) fail(ctx context.Context, key string) {
+	if l.cfg.MaxFailures <= 0 {
+		return
+	}
+	now := l.now()
+	s, err := l.cfg.Store.Load(ctx, key)
+	if err != nil {
+		log.Printf("failed to load failure counter: %v", err)
+		return
+	}
+	s.Count++
+	s.Updated = now
+	ttl := l.cfg.FailureWindow
+	if over := int(s.Count) - l.cfg.MaxFailures; over >= 0 {
+		lockout := l.cfg.MaxLockout
+		if over < 32 {
+			lockout = min(l.cfg.Lockout<<over, l.cfg.MaxLockout)
+		}
+		s.LockedUntil = now.Add(lockout)
+		ttl = max(ttl, lockout)
+	}
+	if err := l.cfg.Store.Store(ctx, key, s, ttl); err != nil {
+		log.Printf("failed to store failure counter: %v", err)
+	}
+}
+
+func (l *rateLimiter) reset(ctx context.Context, key string) {
+	if l.cfg.MaxFailures <= 0 {
+		return
+	}
+	if err := l.cfg.Store.Store(ctx, key, CounterState{}, time.Second); err != nil {
+		log.Printf("failed to reset failure counter: %v", err)
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	secs := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(secs, 1)))
+	http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+}
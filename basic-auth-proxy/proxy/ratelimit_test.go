@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(t *testing.T, cfg RateLimitConfig) (*rateLimiter, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	store := NewMemoryCounterStore()
+	store.now = clock.now
+	cfg.Store = store
+	buckets := NewMemoryCounterStore()
+	buckets.now = clock.now
+	cfg.Buckets = buckets
+	auth := &BasicAuth{Store: MapStore{
+		"alice": {Name: "alice", PasswordHash: bcryptHash(t, "pass")},
+		"bob":   {Name: "bob", PasswordHash: bcryptHash(t, "pass")},
+	}}
+	l := newRateLimiter(auth, cfg)
+	l.now = clock.now
+	return l, clock
+}
+
+// attempt sends a login from ip and returns the response status and Retry-After header.
+func attempt(l *rateLimiter, ip, user, password string) (int, string) {
+	req := httptest.NewRequest("GET", "https://proxy.example.com/", nil)
+	req.Header.Set("CF-Connecting-IP", ip)
+	if user != "" {
+		req.SetBasicAuth(user, password)
+	}
+	rec := httptest.NewRecorder()
+	if _, ok := l.Authenticate(rec, req); ok {
+		return http.StatusOK, ""
+	}
+	return rec.Code, rec.Header().Get("Retry-After")
+}
+
+func TestTokenBucket(t *testing.T) {
+	l, clock := newTestLimiter(t, RateLimitConfig{Rate: 2, Burst: 3})
+	for i := 0; i < 3; i++ {
+		if code, _ := attempt(l, "192.0.2.1", "alice", "pass"); code != http.StatusOK {
+			t.Fatalf("request %d: got status %d, want %d", i, code, http.StatusOK)
+		}
+	}
+	code, retryAfter := attempt(l, "192.0.2.1", "alice", "pass")
+	if code != http.StatusTooManyRequests || retryAfter != "1" {
+		t.Errorf("burst exceeded: got %d with Retry-After %q, want 429 with 1", code, retryAfter)
+	}
+	if code, _ := attempt(l, "192.0.2.2", "alice", "pass"); code != http.StatusOK {
+		t.Errorf("other IP: got status %d, want %d", code, http.StatusOK)
+	}
+	clock.advance(500 * time.Millisecond)
+	if code, _ := attempt(l, "192.0.2.1", "alice", "pass"); code != http.StatusOK {
+		t.Errorf("after refill: got status %d, want %d", code, http.StatusOK)
+	}
+	if code, _ := attempt(l, "192.0.2.1", "alice", "pass"); code != http.StatusTooManyRequests {
+		t.Errorf("after refill: got status %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+func TestTokenBucketStore(t *testing.T) {
+	l, _ := newTestLimiter(t, RateLimitConfig{Rate: 2, Burst: 3, MaxFailures: 5})
+	attempt(l, "192.0.2.1", "alice", "pass")
+	attempt(l, "192.0.2.1", "alice", "wrong")
+	failures := l.cfg.Store.(*MemoryCounterStore)
+	if _, ok := failures.counters["bucket:ip:192.0.2.1"]; ok {
+		t.Error("token buckets must not be written to the failure store")
+	}
+	if _, ok := failures.counters["failures:ip:192.0.2.1"]; !ok {
+		t.Error("failures must be written to the failure store")
+	}
+	if _, ok := l.cfg.Buckets.(*MemoryCounterStore).counters["bucket:ip:192.0.2.1"]; !ok {
+		t.Error("token buckets must be written to the bucket store")
+	}
+}
+
+func TestMemoryCounterStoreSweep(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	store := NewMemoryCounterStore()
+	store.now = clock.now
+	ctx := context.Background()
+	// Keys of clients which never come back are reclaimed without being loaded.
+	for i := 0; i < sweepInterval; i++ {
+		store.Store(ctx, fmt.Sprintf("bucket:ip:192.0.2.%d", i), CounterState{Count: 1}, time.Second)
+	}
+	clock.advance(2 * time.Second)
+	store.Store(ctx, "failures:user:alice", CounterState{Count: 1}, time.Minute)
+	for i := 1; i < sweepInterval; i++ {
+		store.Store(ctx, "bucket:ip:198.51.100.1", CounterState{Count: 1}, time.Second)
+	}
+	if n := len(store.counters); n != 2 {
+		t.Errorf("%d counters left after a sweep, want 2", n)
+	}
+	if s, _ := store.Load(ctx, "failures:user:alice"); s.Count != 1 {
+		t.Errorf("unexpired counter was swept: %+v", s)
+	}
+}
+
+func TestLockout(t *testing.T) {
+	l, clock := newTestLimiter(t, RateLimitConfig{MaxFailures: 3, Lockout: time.Minute, MaxLockout: 3 * time.Minute})
+	for i := 0; i < 3; i++ {
+		if code, _ := attempt(l, "192.0.2.1", "alice", "wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: got status %d, want %d", i, code, http.StatusUnauthorized)
+		}
+	}
+	// Locked out even with the right password.
+	code, retryAfter := attempt(l, "192.0.2.1", "alice", "pass")
+	if code != http.StatusTooManyRequests || retryAfter != "60" {
+		t.Errorf("locked out: got %d with Retry-After %q, want 429 with 60", code, retryAfter)
+	}
+	// The username is locked out from other IPs, and the IP for other users.
+	if code, _ := attempt(l, "192.0.2.2", "alice", "pass"); code != http.StatusTooManyRequests {
+		t.Errorf("same user from other IP: got status %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code, _ := attempt(l, "192.0.2.1", "bob", "pass"); code != http.StatusTooManyRequests {
+		t.Errorf("other user from same IP: got status %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code, _ := attempt(l, "192.0.2.2", "bob", "pass"); code != http.StatusOK {
+		t.Errorf("other user from other IP: got status %d, want %d", code, http.StatusOK)
+	}
+
+	// Each further failure doubles the lockout up to MaxLockout.
+	for _, want := range []string{"120", "180", "180"} {
+		clock.advance(3 * time.Minute)
+		attempt(l, "192.0.2.3", "alice", "wrong")
+		if _, retryAfter := attempt(l, "192.0.2.3", "alice", "pass"); retryAfter != want {
+			t.Errorf("got Retry-After %q, want %q", retryAfter, want)
+		}
+	}
+
+	// Failures are forgotten after the failure window.
+	clock.advance(time.Hour)
+	if code, _ := attempt(l, "192.0.2.1", "alice", "pass"); code != http.StatusOK {
+		t.Errorf("after window: got status %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestLockoutResetOnSuccess(t *testing.T) {
+	l, _ := newTestLimiter(t, RateLimitConfig{MaxFailures: 2})
+	attempt(l, "192.0.2.1", "alice", "wrong")
+	attempt(l, "192.0.2.2", "alice", "pass")
+	attempt(l, "192.0.2.3", "alice", "wrong")
+	if code, _ := attempt(l, "192.0.2.4", "alice", "pass"); code != http.StatusOK {
+		t.Errorf("got status %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestLockoutIgnoresChallenges(t *testing.T) {
+	l, _ := newTestLimiter(t, RateLimitConfig{MaxFailures: 1})
+	for i := 0; i < 3; i++ {
+		if code, _ := attempt(l, "192.0.2.1", "", ""); code != http.StatusUnauthorized {
+			t.Fatalf("got status %d, want %d", code, http.StatusUnauthorized)
+		}
+	}
+	if code, _ := attempt(l, "192.0.2.1", "alice", "pass"); code != http.StatusOK {
+		t.Errorf("requests without credentials must not count as failures, got status %d", code)
+	}
+}
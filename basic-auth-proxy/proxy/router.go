@@ -0,0 +1,262 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Route forwards requests matching Host and PathPrefix to Upstreams.
+//
+// When several routes match a request, a route with an exact host wins over
+// one with a wildcard host, which wins over one without a host. Among those,
+// the longest path prefix wins, and then the route listed first.
+type Route struct {
+	// Host matches the request host exactly, or any subdomain if it starts with "*.".
+	// An empty host matches all hosts.
+	Host string `json:"host,omitempty"`
+	// PathPrefix matches the request path by whole segments, so "/api" matches
+	// "/api" and "/api/users" but not "/apis". An empty prefix matches all paths.
+	PathPrefix string `json:"path_prefix,omitempty"`
+	// Upstreams are tried in order, skipping upstreams that recently failed.
+	Upstreams []string `json:"upstreams"`
+	// Auth is "required" (default) or "none" for public routes.
+	Auth string `json:"auth,omitempty"`
+	// Rewrite replaces PathPrefix in the forwarded path if not empty,
+	// e.g. "/" to strip the prefix.
+	Rewrite string `json:"rewrite,omitempty"`
+}
+
+// RoutesConfig is the JSON configuration of routes.
+type RoutesConfig struct {
+	Routes []Route `json:"routes"`
+}
+
+// ParseRoutes parses routes from JSON such as:
+//
+//	{"routes": [
+//	  {"host": "api.example.com", "upstreams": ["https://api-1.internal", "https://api-2.internal"]},
+//	  {"path_prefix": "/docs", "upstreams": ["https://docs.internal"], "auth": "none", "rewrite": "/"}
+//	]}
+func ParseRoutes(data []byte) ([]Route, error) {
+	var cfg RoutesConfig
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid routes: %w", err)
+	}
+	for i := range cfg.Routes {
+		if _, err := newRoute(cfg.Routes[i]); err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+	}
+	return cfg.Routes, nil
+}
+
+// route is a compiled Route.
+type route struct {
+	Route
+	public    bool
+	upstreams []*upstream
+}
+
+func newRoute(r Route) (*route, error) {
+	rt := &route{Route: r}
+	switch r.Auth {
+	case "", "required":
+	case "none":
+		rt.public = true
+	default:
+		return nil, fmt.Errorf("unknown auth %q", r.Auth)
+	}
+	if r.PathPrefix != "" && !strings.HasPrefix(r.PathPrefix, "/") {
+		return nil, fmt.Errorf("path prefix %q must start with /", r.PathPrefix)
+	}
+	rt.PathPrefix = strings.TrimSuffix(r.PathPrefix, "/")
+	rt.Host = strings.ToLower(r.Host)
+	if len(r.Upstreams) == 0 {
+		return nil, errors.New("no upstreams")
+	}
+	for _, raw := range r.Upstreams {
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("upstream %q must be an absolute URL", raw)
+		}
+		rt.upstreams = append(rt.upstreams, &upstream{url: u})
+	}
+	return rt, nil
+}
+
+// hostRank ranks how specifically the route matches host, or returns -1 if it does not match.
+func (rt *route) hostRank(host string) int {
+	switch {
+	case rt.Host == "":
+		return 0
+	case strings.HasPrefix(rt.Host, "*."):
+		if strings.HasSuffix(host, rt.Host[1:]) {
+			return 1
+		}
+	case rt.Host == host:
+		return 2
+	}
+	return -1
+}
+
+func (rt *route) matchesPath(path string) bool {
+	return rt.PathPrefix == "" || path == rt.PathPrefix || strings.HasPrefix(path, rt.PathPrefix+"/")
+}
+
+// forwardPath returns the path forwarded to the upstream.
+func (rt *route) forwardPath(path string) string {
+	if rt.Rewrite == "" {
+		return path
+	}
+	rest := strings.TrimPrefix(path, rt.PathPrefix)
+	return strings.TrimSuffix(rt.Rewrite, "/") + "/" + strings.TrimPrefix(rest, "/")
+}
+
+// match returns the route for req, or nil if none matches.
+func match(routes []*route, req *http.Request) *route {
+	host := strings.ToLower(req.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	var best *route
+	bestHost := -1
+	for _, rt := range routes {
+		rank := rt.hostRank(host)
+		if rank < 0 || !rt.matchesPath(req.URL.Path) {
+			continue
+		}
+		if rank > bestHost || (rank == bestHost && len(rt.PathPrefix) > len(best.PathPrefix)) {
+			best, bestHost = rt, rank
+		}
+	}
+	return best
+}
+
+// forwardURL returns the URL of the upstream u for the request path and query.
+func forwardURL(u *url.URL, req *http.Request, path string) *url.URL {
+	out := &http.Request{URL: &url.URL{Path: path, RawQuery: req.URL.RawQuery}}
+	if path == req.URL.Path {
+		out.URL.RawPath = req.URL.RawPath
+	}
+	pr := &httputil.ProxyRequest{In: req, Out: out}
+	pr.SetURL(u)
+	return out.URL
+}
+
+const (
+	// minDowntime is how long an upstream is skipped after its first failure,
+	// doubled for each consecutive failure up to maxDowntime.
+	minDowntime = 5 * time.Second
+	maxDowntime = 5 * time.Minute
+)
+
+// upstream is an upstream URL with its passive health state.
+type upstream struct {
+	url *url.URL
+
+	mu        sync.Mutex
+	failures  int
+	downUntil time.Time
+}
+
+func (u *upstream) healthy(now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return !now.Before(u.downUntil)
+}
+
+func (u *upstream) markFailure(now time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	downtime := maxDowntime
+	if u.failures < 16 {
+		downtime = min(minDowntime<<u.failures, maxDowntime)
+	}
+	u.failures++
+	u.downUntil = now.Add(downtime)
+}
+
+func (u *upstream) markSuccess() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.failures = 0
+	u.downUntil = time.Time{}
+}
+
+// candidates returns the healthy upstreams of rt in order, followed by
+// the unhealthy ones as a last resort.
+func (rt *route) candidates(now time.Time) []*upstream {
+	healthy := make([]*upstream, 0, len(rt.upstreams))
+	var down []*upstream
+	for _, u := range rt.upstreams {
+		if u.healthy(now) {
+			healthy = append(healthy, u)
+		} else {
+			down = append(down, u)
+		}
+	}
+	return append(healthy, down...)
+}
+
+// failoverTransport sends a request to the upstreams of its route in turn
+// until one responds without a gateway error.
+type failoverTransport struct {
+	base http.RoundTripper
+	now  func() time.Time
+}
+
+func (t *failoverTransport) RoundTrip(out *http.Request) (*http.Response, error) {
+	st := requestStateFrom(out.Context())
+	if st == nil || len(st.candidates) == 0 {
+		return t.base.RoundTrip(out)
+	}
+	candidates := st.candidates
+	// A request body can only be sent once.
+	if out.Body != nil && out.Body != http.NoBody && out.GetBody == nil {
+		candidates = candidates[:1]
+	}
+	var lastErr error
+	for i, u := range candidates {
+		req := out
+		if i > 0 {
+			req = out.Clone(out.Context())
+			req.URL = forwardURL(u.url, st.in, st.forwardPath)
+			req.Host = ""
+			if out.GetBody != nil {
+				body, err := out.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+		resp, err := t.base.RoundTrip(req)
+		if err == nil && !isGatewayError(resp.StatusCode) {
+			u.markSuccess()
+			return resp, nil
+		}
+		u.markFailure(t.now())
+		if i == len(candidates)-1 {
+			return resp, err
+		}
+		if err == nil {
+			resp.Body.Close()
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func isGatewayError(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
@@ -0,0 +1,239 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMatchPrecedence(t *testing.T) {
+	routes := []Route{
+		{Upstreams: []string{"https://default"}},
+		{PathPrefix: "/api", Upstreams: []string{"https://api"}},
+		{PathPrefix: "/api/v2", Upstreams: []string{"https://api-v2"}},
+		{PathPrefix: "/api", Upstreams: []string{"https://api-duplicate"}},
+		{Host: "*.example.com", Upstreams: []string{"https://wildcard"}},
+		{Host: "*.example.com", PathPrefix: "/api", Upstreams: []string{"https://wildcard-api"}},
+		{Host: "app.example.com", Upstreams: []string{"https://app"}},
+		{Host: "app.example.com", PathPrefix: "/static/", Upstreams: []string{"https://app-static"}},
+	}
+	var compiled []*route
+	for _, r := range routes {
+		rt, err := newRoute(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compiled = append(compiled, rt)
+	}
+	tests := map[string]string{
+		"https://other.test/":                    "https://default",
+		"https://other.test/apis":                "https://default",
+		"https://other.test/api":                 "https://api",
+		"https://other.test/api/users":           "https://api",
+		"https://other.test/api/v2/users":        "https://api-v2",
+		"https://www.example.com/api/users":      "https://wildcard-api",
+		"https://www.example.com/":               "https://wildcard",
+		"https://example.com/":                   "https://default",
+		"https://APP.example.com:8443/api/users": "https://app",
+		"https://app.example.com/static":         "https://app-static",
+		"https://app.example.com/static/app.js":  "https://app-static",
+	}
+	for target, want := range tests {
+		rt := match(compiled, httptest.NewRequest("GET", target, nil))
+		if rt == nil {
+			t.Errorf("%s: no route, want %s", target, want)
+			continue
+		}
+		if got := rt.Upstreams[0]; got != want {
+			t.Errorf("%s: got %s, want %s", target, got, want)
+		}
+	}
+	if rt := match(compiled[1:3], httptest.NewRequest("GET", "https://other.test/", nil)); rt != nil {
+		t.Errorf("want no route, got %s", rt.Upstreams[0])
+	}
+}
+
+func TestForwardPath(t *testing.T) {
+	tests := []struct {
+		prefix, rewrite, path, want string
+	}{
+		{"/api", "", "/api/users", "/api/users"},
+		{"/api", "/", "/api/users", "/users"},
+		{"/api", "/", "/api", "/"},
+		{"/api/", "/v2/", "/api/users", "/v2/users"},
+		{"/api", "/v2", "/api", "/v2/"},
+		{"", "/root", "/users", "/root/users"},
+	}
+	for _, tt := range tests {
+		rt, err := newRoute(Route{PathPrefix: tt.prefix, Rewrite: tt.rewrite, Upstreams: []string{"https://u"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rt.forwardPath(tt.path); got != tt.want {
+			t.Errorf("prefix %q rewrite %q: forwardPath(%q) = %q, want %q", tt.prefix, tt.rewrite, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes([]byte(`{"routes": [
+		{"host": "api.example.com", "upstreams": ["https://a.internal", "https://b.internal"]},
+		{"path_prefix": "/docs", "upstreams": ["https://docs.internal"], "auth": "none", "rewrite": "/"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 || len(routes[0].Upstreams) != 2 || routes[1].Auth != "none" {
+		t.Errorf("unexpected routes %+v", routes)
+	}
+	for name, in := range map[string]string{
+		"not JSON":          `routes`,
+		"unknown field":     `{"routes": [{"upstream": "https://a"}]}`,
+		"no upstreams":      `{"routes": [{"path_prefix": "/"}]}`,
+		"relative upstream": `{"routes": [{"upstreams": ["/a"]}]}`,
+		"unknown auth":      `{"routes": [{"upstreams": ["https://a"], "auth": "maybe"}]}`,
+		"relative prefix":   `{"routes": [{"path_prefix": "api", "upstreams": ["https://a"]}]}`,
+	} {
+		if _, err := ParseRoutes([]byte(in)); err == nil {
+			t.Errorf("%s: want error, got nil", name)
+		}
+	}
+}
+
+// countingServer returns a server responding with status and counting its requests.
+func countingServer(t *testing.T, status int, hits *atomic.Int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		hits.Add(1)
+		w.Header().Set("X-Path", req.URL.Path)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFailover(t *testing.T) {
+	var primaryHits, backupHits atomic.Int32
+	primaryStatus := http.StatusServiceUnavailable
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		primaryHits.Add(1)
+		w.WriteHeader(primaryStatus)
+	}))
+	defer primary.Close()
+	backup := countingServer(t, http.StatusOK, &backupHits)
+
+	p, err := New(Config{
+		Routes: []Route{{PathPrefix: "/api", Rewrite: "/", Upstreams: []string{primary.URL, backup.URL}}},
+		Store:  MapStore{"alice": {Name: "alice", PasswordHash: bcryptHash(t, "pass")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	p.now = clock.now
+	p.rp.Transport.(*failoverTransport).now = clock.now
+
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, newAuthRequest("GET", "https://proxy.example.com/api/users", nil))
+		return rec
+	}
+	rec := get()
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Path") != "/users" {
+		t.Fatalf("got %d from %q, want 200 from the backup at /users", rec.Code, rec.Header().Get("X-Path"))
+	}
+	if primaryHits.Load() != 1 || backupHits.Load() != 1 {
+		t.Fatalf("got %d primary and %d backup hits, want 1 and 1", primaryHits.Load(), backupHits.Load())
+	}
+
+	// The failed primary is skipped while it is down.
+	get()
+	if primaryHits.Load() != 1 || backupHits.Load() != 2 {
+		t.Errorf("got %d primary and %d backup hits, want 1 and 2", primaryHits.Load(), backupHits.Load())
+	}
+
+	// The primary is tried again after its downtime.
+	primaryStatus = http.StatusOK
+	clock.advance(minDowntime)
+	get()
+	if primaryHits.Load() != 2 || backupHits.Load() != 2 {
+		t.Errorf("got %d primary and %d backup hits, want 2 and 2", primaryHits.Load(), backupHits.Load())
+	}
+}
+
+func TestFailoverAllDown(t *testing.T) {
+	var hits atomic.Int32
+	a := countingServer(t, http.StatusBadGateway, &hits)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	newProxy := func() *Proxy {
+		p, err := New(Config{
+			Routes: []Route{{Upstreams: []string{closed.URL, a.URL}, Auth: "none"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	p := newProxy()
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "https://proxy.example.com/", nil))
+	if rec.Code != http.StatusBadGateway || hits.Load() != 1 {
+		t.Errorf("got status %d after %d hits, want 502 from the last upstream", rec.Code, hits.Load())
+	}
+
+	// A request body cannot be replayed, so only the first upstream is tried.
+	hits.Store(0)
+	rec = httptest.NewRecorder()
+	newProxy().ServeHTTP(rec, httptest.NewRequest("POST", "https://proxy.example.com/", io.NopCloser(strings.NewReader("body"))))
+	if rec.Code != http.StatusBadGateway || hits.Load() != 0 {
+		t.Errorf("got status %d after %d hits, want 502 without trying the second upstream", rec.Code, hits.Load())
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	var publicHits, privateHits atomic.Int32
+	public := countingServer(t, http.StatusOK, &publicHits)
+	private := countingServer(t, http.StatusOK, &privateHits)
+	p, err := New(Config{
+		Routes: []Route{
+			{PathPrefix: "/public", Upstreams: []string{public.URL}, Auth: "none"},
+			{PathPrefix: "/private", Upstreams: []string{private.URL}},
+		},
+		Store: MapStore{"alice": {Name: "alice", PasswordHash: bcryptHash(t, "pass"), Upstreams: []string{public.URL}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		req  *http.Request
+		want int
+	}{
+		{httptest.NewRequest("GET", "https://proxy.example.com/public/page", nil), http.StatusOK},
+		{httptest.NewRequest("GET", "https://proxy.example.com/private", nil), http.StatusUnauthorized},
+		{newAuthRequest("GET", "https://proxy.example.com/private", nil), http.StatusForbidden},
+		{httptest.NewRequest("GET", "https://proxy.example.com/other", nil), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, tt.req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.req.URL.Path, rec.Code, tt.want)
+		}
+	}
+	if publicHits.Load() != 1 || privateHits.Load() != 0 {
+		t.Errorf("got %d public and %d private hits, want 1 and 0", publicHits.Load(), privateHits.Load())
+	}
+
+	if _, err := New(Config{Routes: []Route{{Upstreams: []string{public.URL}}}}); err == nil {
+		t.Error("routes requiring auth without a store: want error, got nil")
+	}
+	u, _ := url.Parse(public.URL)
+	if _, err := New(Config{Upstream: u, Routes: []Route{{Upstreams: []string{public.URL}, Auth: "none"}}}); err != nil {
+		t.Errorf("public routes without a store: %v", err)
+	}
+}
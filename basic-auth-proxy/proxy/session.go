@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var errInvalidToken = errors.New("invalid signed token")
+
+// signer signs values into tamper-proof tokens for cookies.
+// A token is base64url(JSON payload) + "." + base64url(HMAC-SHA256).
+//
+// The MAC covers the purpose of the token, such as purposeSession,
+// so that a token issued for one cookie is rejected as another.
+type signer struct {
+	key []byte
+	now func() time.Time
+}
+
+// Purposes of signed tokens.
+const (
+	purposeSession    = "session"
+	purposeLoginState = "oidc-state"
+)
+
+type signedPayload struct {
+	Exp   int64           `json:"exp"`
+	Value json.RawMessage `json:"v"`
+}
+
+// sign returns a token for purpose holding v which expires after ttl.
+func (s *signer) sign(purpose string, v any, ttl time.Duration) (string, error) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(signedPayload{
+		Exp:   s.now().Add(ttl).Unix(),
+		Value: value,
+	})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(purpose, encoded)), nil
+}
+
+// verify checks the signature, purpose and expiry of token and decodes its value into v.
+func (s *signer) verify(purpose, token string, v any) error {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return errInvalidToken
+	}
+	gotMAC, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotMAC, s.mac(purpose, encoded)) {
+		return errInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return errInvalidToken
+	}
+	var p signedPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return errInvalidToken
+	}
+	if s.now().Unix() >= p.Exp {
+		return errors.New("signed token expired")
+	}
+	return json.Unmarshal(p.Value, v)
+}
+
+func (s *signer) mac(purpose, data string) []byte {
+	m := hmac.New(sha256.New, s.key)
+	// The purpose cannot contain NUL, so purpose and data cannot be shifted into each other.
+	m.Write([]byte(purpose))
+	m.Write([]byte{0})
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/syumai/basic-auth-proxy/proxy"
+	"github.com/syumai/workers/cloudflare"
+)
+
+const (
+	// usersEnvName is a secret or var holding users in the htpasswd format.
+	usersEnvName = "BASIC_AUTH_USERS"
+	// usersKVName is a KV namespace binding holding a "user:<name>" key per user.
+	usersKVName = "USERS"
+)
+
+// loadStore returns the KV store if the USERS binding exists,
+// and the users parsed from BASIC_AUTH_USERS otherwise.
+func loadStore(ctx context.Context) (proxy.Store, error) {
+	if !cloudflare.GetBinding(ctx, usersKVName).IsUndefined() {
+		kv, err := cloudflare.NewKVNamespace(ctx, usersKVName)
+		if err != nil {
+			return nil, err
+		}
+		return &kvStore{kv: kv}, nil
+	}
+	users := cloudflare.GetBinding(ctx, usersEnvName)
+	if users.IsUndefined() || users.String() == "" {
+		return nil, errors.New(usersEnvName + " is not set")
+	}
+	return envStore.load(users.String())
+}
+
+// runtimeStore is a proxy.Store looking up users in the store
+// configured in the environment of the request context.
+type runtimeStore struct{}
+
+func (runtimeStore) Lookup(ctx context.Context, name string) (*proxy.User, error) {
+	store, err := loadStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.Lookup(ctx, name)
+}
+
+// envStore caches the users parsed from the environment for the isolate's lifetime.
+var envStore cachedStore
+
+type cachedStore struct {
+	mu    sync.Mutex
+	src   string
+	store proxy.MapStore
+}
+
+func (c *cachedStore) load(src string) (proxy.Store, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store != nil && c.src == src {
+		return c.store, nil
+	}
+	store, err := proxy.ParseHtpasswd(strings.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	c.src, c.store = src, store
+	return store, nil
+}
+
+// kvStore looks up users stored in KV. The value of "user:<name>" is
+// the rest of an htpasswd line: "<hash> [upstream...]".
+type kvStore struct {
+	kv *cloudflare.KVNamespace
+}
+
+func (s *kvStore) Lookup(ctx context.Context, name string) (*proxy.User, error) {
+	v, err := s.kv.GetString("user:"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	// GetString converts the null returned for a missing key to "<null>".
+	if v == "<null>" || v == "" {
+		return nil, proxy.ErrUserNotFound
+	}
+	return proxy.ParseHtpasswdEntry(name + ":" + v)
+}
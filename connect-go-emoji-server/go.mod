@@ -6,5 +6,6 @@ require (
 	github.com/bufbuild/connect-go v1.5.2
 	github.com/syumai/emo v0.2.1
 	github.com/syumai/workers v0.10.1
+	google.golang.org/genproto v0.0.0-20221118155620-16455021b5e6
 	google.golang.org/protobuf v1.28.1
 )
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	_ "embed"
 	"errors"
 	"net/http"
 
@@ -10,8 +11,12 @@ import (
 	"github.com/syumai/workers"
 	emojiv1 "github.com/syumai/workers-playground/connect-go-emoji-server/gen/emoji/v1"
 	"github.com/syumai/workers-playground/connect-go-emoji-server/gen/emoji/v1/emojiv1connect"
+	"github.com/syumai/workers-playground/connect-go-emoji-server/rest"
 )
 
+//go:embed gen/openapi/openapi.swagger.json
+var openAPIDoc []byte
+
 type EmojiServer struct{}
 
 var _ emojiv1connect.EmojiServiceHandler = (*EmojiServer)(nil)
@@ -33,5 +38,17 @@ func main() {
 	srv := &EmojiServer{}
 	path, handler := emojiv1connect.NewEmojiServiceHandler(srv)
 	http.Handle(path, handler)
+
+	sd := emojiv1.File_emoji_v1_emoji_proto.Services().ByName("EmojiService")
+	transcoder, err := rest.NewTranscoder(sd, handler)
+	if err != nil {
+		panic(err)
+	}
+	http.Handle("/v1/", transcoder)
+
+	http.HandleFunc("/openapi.json", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPIDoc)
+	})
 	workers.Serve(nil)
 }
@@ -0,0 +1,414 @@
+// Package rest serves Connect services as REST, transcoded from google.api.http annotations.
+package rest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/connect-go"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// restRoute is a REST binding built from a google.api.http annotation.
+type restRoute struct {
+	method    string
+	segments  []string
+	body      string
+	procedure string
+	input     protoreflect.MessageDescriptor
+}
+
+// Transcoder translates REST requests into Connect unary JSON requests
+// and forwards them to the Connect handler.
+type Transcoder struct {
+	routes []*restRoute
+	next   http.Handler
+}
+
+var _ http.Handler = (*Transcoder)(nil)
+
+// NewTranscoder returns a Transcoder for the methods of sd with a google.api.http annotation.
+// Requests matching none of them are passed to next.
+func NewTranscoder(sd protoreflect.ServiceDescriptor, next http.Handler) (*Transcoder, error) {
+	t := &Transcoder{next: next}
+	methods := sd.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		md := methods.Get(i)
+		rule, ok := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule)
+		if !ok || rule == nil {
+			continue
+		}
+		procedure := "/" + string(sd.FullName()) + "/" + string(md.Name())
+		for _, r := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+			route, err := newRESTRoute(r, procedure, md.Input())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", procedure, err)
+			}
+			t.routes = append(t.routes, route)
+		}
+	}
+	return t, nil
+}
+
+func newRESTRoute(rule *annotations.HttpRule, procedure string, input protoreflect.MessageDescriptor) (*restRoute, error) {
+	var method, path string
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		method, path = http.MethodGet, p.Get
+	case *annotations.HttpRule_Put:
+		method, path = http.MethodPut, p.Put
+	case *annotations.HttpRule_Post:
+		method, path = http.MethodPost, p.Post
+	case *annotations.HttpRule_Delete:
+		method, path = http.MethodDelete, p.Delete
+	case *annotations.HttpRule_Patch:
+		method, path = http.MethodPatch, p.Patch
+	case *annotations.HttpRule_Custom:
+		method, path = p.Custom.GetKind(), p.Custom.GetPath()
+	default:
+		return nil, errors.New("http rule has no pattern")
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, seg := range segments {
+		if name, ok := pathVariable(seg); ok && input.Fields().ByName(protoreflect.Name(name)) == nil {
+			return nil, fmt.Errorf("path variable %q is not a field of %s", name, input.FullName())
+		}
+	}
+	return &restRoute{
+		method:    method,
+		segments:  segments,
+		body:      rule.GetBody(),
+		procedure: procedure,
+		input:     input,
+	}, nil
+}
+
+// pathVariable reports the field name of a path template segment such as
+// "{short_name}" or "{short_name=*}".
+func pathVariable(seg string) (string, bool) {
+	if !strings.HasPrefix(seg, "{") || !strings.HasSuffix(seg, "}") {
+		return "", false
+	}
+	name, _, _ := strings.Cut(seg[1:len(seg)-1], "=")
+	return name, true
+}
+
+// match returns the path variables of the request if it matches the route.
+func (r *restRoute) match(req *http.Request) (map[string]string, bool) {
+	if req.Method != r.method {
+		return nil, false
+	}
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	vars := map[string]string{}
+	for i, seg := range r.segments {
+		if name, ok := pathVariable(seg); ok {
+			if segments[i] == "" {
+				return nil, false
+			}
+			vars[name] = segments[i]
+			continue
+		}
+		if seg != segments[i] {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+func (t *Transcoder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	for _, route := range t.routes {
+		vars, ok := route.match(req)
+		if !ok {
+			continue
+		}
+		t.serveRoute(w, req, route, vars)
+		return
+	}
+	t.next.ServeHTTP(w, req)
+}
+
+func (t *Transcoder) serveRoute(w http.ResponseWriter, req *http.Request, route *restRoute, vars map[string]string) {
+	msg, err := route.buildRequest(req, vars)
+	if err != nil {
+		writeStatus(w, connect.CodeInvalidArgument, err.Error())
+		return
+	}
+	reqBody, err := protojson.Marshal(msg)
+	if err != nil {
+		writeStatus(w, connect.CodeInternal, err.Error())
+		return
+	}
+	connectReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, route.procedure, bytes.NewReader(reqBody))
+	if err != nil {
+		writeStatus(w, connect.CodeInternal, err.Error())
+		return
+	}
+	for k, v := range req.Header {
+		switch http.CanonicalHeaderKey(k) {
+		case "Content-Type", "Content-Length", "Accept-Encoding":
+		default:
+			connectReq.Header[k] = v
+		}
+	}
+	connectReq.Header.Set("Content-Type", "application/json")
+
+	rec := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+	t.next.ServeHTTP(rec, connectReq)
+	copyHeader(w.Header(), rec.header)
+	if rec.status != http.StatusOK {
+		var connectErr struct {
+			Code    connect.Code `json:"code"`
+			Message string       `json:"message"`
+		}
+		if err := json.Unmarshal(rec.body.Bytes(), &connectErr); err != nil {
+			writeStatus(w, connect.CodeUnknown, strings.TrimSpace(rec.body.String()))
+			return
+		}
+		writeStatus(w, connectErr.Code, connectErr.Message)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, &rec.body)
+}
+
+// copyHeader copies the headers of the Connect response except those describing its body.
+// Connect sends unary trailers as headers prefixed with "Trailer-", and they are copied as is.
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		switch http.CanonicalHeaderKey(k) {
+		case "Content-Type", "Content-Length", "Content-Encoding":
+		default:
+			dst[k] = v
+		}
+	}
+}
+
+// buildRequest fills the input message from the request body, path variables
+// and query parameters. Query parameters never override bound fields.
+func (r *restRoute) buildRequest(req *http.Request, vars map[string]string) (protoreflect.ProtoMessage, error) {
+	msg := dynamicpb.NewMessage(r.input)
+	bound := map[string]bool{}
+	switch r.body {
+	case "":
+	case "*":
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > 0 {
+			if err := protojson.Unmarshal(b, msg); err != nil {
+				return nil, fmt.Errorf("invalid request body: %w", err)
+			}
+		}
+		return msg, setPathVariables(msg, vars)
+	default:
+		fd := r.input.Fields().ByName(protoreflect.Name(r.body))
+		if fd == nil || fd.Message() == nil {
+			return nil, fmt.Errorf("body field %q is not a message field", r.body)
+		}
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		field := msg.Mutable(fd).Message()
+		if err := protojson.Unmarshal(b, field.Interface()); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		bound[r.body] = true
+	}
+	if err := setPathVariables(msg, vars); err != nil {
+		return nil, err
+	}
+	for name := range vars {
+		bound[name] = true
+	}
+	for key, values := range req.URL.Query() {
+		for _, v := range values {
+			if err := setField(msg, key, v, bound); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return msg, nil
+}
+
+func setPathVariables(msg protoreflect.Message, vars map[string]string) error {
+	for name, v := range vars {
+		if err := setField(msg, name, v, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setField sets a field addressed by a dotted path of proto or JSON names.
+// Fields already bound from the path or body are skipped.
+func setField(msg protoreflect.Message, path, value string, bound map[string]bool) error {
+	names := strings.Split(path, ".")
+	var protoPath []string
+	for i, name := range names {
+		fields := msg.Descriptor().Fields()
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil {
+			return fmt.Errorf("unknown field %q", path)
+		}
+		protoPath = append(protoPath, string(fd.Name()))
+		if bound[strings.Join(protoPath, ".")] {
+			return nil
+		}
+		if i < len(names)-1 {
+			if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+				return fmt.Errorf("field %q is not a message", path)
+			}
+			msg = msg.Mutable(fd).Message()
+			continue
+		}
+		if fd.IsMap() || fd.Message() != nil {
+			return fmt.Errorf("field %q cannot be set from a string", path)
+		}
+		v, err := parseScalar(fd, value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %q: %w", path, err)
+		}
+		if fd.IsList() {
+			msg.Mutable(fd).List().Append(v)
+		} else {
+			msg.Set(fd, v)
+		}
+	}
+	return nil
+}
+
+func parseScalar(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BytesKind:
+		b, err := base64.StdEncoding.DecodeString(s)
+		return protoreflect.ValueOfBytes(b), err
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(s, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(s, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), err
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported kind %s", fd.Kind())
+}
+
+// rpcStatus is the JSON error body returned to REST clients,
+// matching google.rpc.Status.
+type rpcStatus struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message"`
+	Details []any  `json:"details"`
+}
+
+func writeStatus(w http.ResponseWriter, code connect.Code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatusFromCode(code))
+	json.NewEncoder(w).Encode(&rpcStatus{
+		Code:    int32(code),
+		Message: msg,
+		Details: []any{},
+	})
+}
+
+// httpStatusFromCode maps a Connect error code to the HTTP status used by
+// google.api.http transcoding.
+func httpStatusFromCode(code connect.Code) int {
+	switch code {
+	case connect.CodeCanceled:
+		return 499
+	case connect.CodeUnknown:
+		return http.StatusInternalServerError
+	case connect.CodeInvalidArgument:
+		return http.StatusBadRequest
+	case connect.CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case connect.CodeNotFound:
+		return http.StatusNotFound
+	case connect.CodeAlreadyExists:
+		return http.StatusConflict
+	case connect.CodePermissionDenied:
+		return http.StatusForbidden
+	case connect.CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case connect.CodeFailedPrecondition:
+		return http.StatusBadRequest
+	case connect.CodeAborted:
+		return http.StatusConflict
+	case connect.CodeOutOfRange:
+		return http.StatusBadRequest
+	case connect.CodeUnimplemented:
+		return http.StatusNotImplemented
+	case connect.CodeInternal:
+		return http.StatusInternalServerError
+	case connect.CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case connect.CodeDataLoss:
+		return http.StatusInternalServerError
+	case connect.CodeUnauthenticated:
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+// bufferedResponse captures the Connect handler's response so it can be
+// rewritten for REST clients.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *bufferedResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *bufferedResponse) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+func (r *bufferedResponse) WriteHeader(status int) {
+	r.status = status
+}
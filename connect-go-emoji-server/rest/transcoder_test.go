@@ -0,0 +1,385 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/connect-go"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	emojiv1 "github.com/syumai/workers-playground/connect-go-emoji-server/gen/emoji/v1"
+	"github.com/syumai/workers-playground/connect-go-emoji-server/gen/emoji/v1/emojiv1connect"
+)
+
+// bookService returns the descriptor of this service:
+//
+//	service BookService {
+//	  rpc GetBook(GetBookRequest) returns (Book) { get: "/v1/books/{name}" }
+//	  rpc CreateBook(CreateBookRequest) returns (Book) { post: "/v1/shelves/{parent}/books" body: "book" }
+//	  rpc UpdateBook(Book) returns (Book) { patch: "/v1/books/{name}" body: "*" }
+//	}
+func bookService(t *testing.T) protoreflect.ServiceDescriptor {
+	t.Helper()
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, repeated bool) *descriptorpb.FieldDescriptorProto {
+		label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		if repeated {
+			label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		}
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(jsonName(name)),
+			Number:   proto.Int32(number),
+			Type:     typ.Enum(),
+			Label:    label.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	const (
+		str = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		i32 = descriptorpb.FieldDescriptorProto_TYPE_INT32
+		bl  = descriptorpb.FieldDescriptorProto_TYPE_BOOL
+		msg = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+	method := func(name, input string, rule *annotations.HttpRule) *descriptorpb.MethodDescriptorProto {
+		opts := &descriptorpb.MethodOptions{}
+		proto.SetExtension(opts, annotations.E_Http, rule)
+		return &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(input),
+			OutputType: proto.String(".test.v1.Book"),
+			Options:    opts,
+		}
+	}
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/v1/book.proto"),
+		Package: proto.String("test.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Book"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, str, "", false),
+				field("title", 2, str, "", false),
+				field("pages", 3, i32, "", false),
+			}},
+			{Name: proto.String("GetBookRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, str, "", false),
+				field("version", 2, i32, "", false),
+				field("fields", 3, str, "", true),
+			}},
+			{Name: proto.String("CreateBookRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("parent", 1, str, "", false),
+				field("book", 2, msg, ".test.v1.Book", false),
+				field("validate_only", 3, bl, "", false),
+			}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("BookService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("GetBook", ".test.v1.GetBookRequest", &annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Get{Get: "/v1/books/{name}"},
+				}),
+				method("CreateBook", ".test.v1.CreateBookRequest", &annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Post{Post: "/v1/shelves/{parent}/books"},
+					Body:    "book",
+				}),
+				method("UpdateBook", ".test.v1.Book", &annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Patch{Patch: "/v1/books/{name}"},
+					Body:    "*",
+				}),
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Services().Get(0)
+}
+
+func jsonName(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.Join(parts, "")
+}
+
+// fakeConnect records the Connect request and writes the configured response.
+type fakeConnect struct {
+	procedure string
+	header    http.Header
+	body      map[string]any
+
+	status     int
+	respHeader http.Header
+	respBody   string
+}
+
+func (f *fakeConnect) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.procedure = req.URL.Path
+	f.header = req.Header
+	f.body = nil
+	json.NewDecoder(req.Body).Decode(&f.body)
+	for k, v := range f.respHeader {
+		w.Header()[k] = v
+	}
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+	}
+	io.WriteString(w, f.respBody)
+}
+
+func newTestTranscoder(t *testing.T) (*Transcoder, *fakeConnect) {
+	t.Helper()
+	next := &fakeConnect{respBody: "{}"}
+	tr, err := NewTranscoder(bookService(t), next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr, next
+}
+
+func serve(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTranscoderRequests(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		target        string
+		body          string
+		wantProcedure string
+		wantBody      map[string]any
+	}{
+		{
+			name:          "path variable",
+			method:        http.MethodGet,
+			target:        "/v1/books/moby",
+			wantProcedure: "/test.v1.BookService/GetBook",
+			wantBody:      map[string]any{"name": "moby"},
+		},
+		{
+			name:          "query parameters",
+			method:        http.MethodGet,
+			target:        "/v1/books/moby?version=3&fields=title&fields=pages",
+			wantProcedure: "/test.v1.BookService/GetBook",
+			wantBody:      map[string]any{"name": "moby", "version": float64(3), "fields": []any{"title", "pages"}},
+		},
+		{
+			name:          "query parameter does not override path variable",
+			method:        http.MethodGet,
+			target:        "/v1/books/moby?name=other",
+			wantProcedure: "/test.v1.BookService/GetBook",
+			wantBody:      map[string]any{"name": "moby"},
+		},
+		{
+			name:          "field body",
+			method:        http.MethodPost,
+			target:        "/v1/shelves/classics/books?validateOnly=true&book.title=ignored",
+			body:          `{"title": "Moby-Dick", "pages": 635}`,
+			wantProcedure: "/test.v1.BookService/CreateBook",
+			wantBody: map[string]any{
+				"parent":       "classics",
+				"book":         map[string]any{"title": "Moby-Dick", "pages": float64(635)},
+				"validateOnly": true,
+			},
+		},
+		{
+			name:          "whole body",
+			method:        http.MethodPatch,
+			target:        "/v1/books/moby",
+			body:          `{"name": "other", "title": "Moby-Dick"}`,
+			wantProcedure: "/test.v1.BookService/UpdateBook",
+			wantBody:      map[string]any{"name": "moby", "title": "Moby-Dick"},
+		},
+		{
+			name:          "empty whole body",
+			method:        http.MethodPatch,
+			target:        "/v1/books/moby",
+			wantProcedure: "/test.v1.BookService/UpdateBook",
+			wantBody:      map[string]any{"name": "moby"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, next := newTestTranscoder(t)
+			rec := serve(tr, tt.method, tt.target, tt.body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			if next.procedure != tt.wantProcedure {
+				t.Errorf("procedure %q, want %q", next.procedure, tt.wantProcedure)
+			}
+			if ct := next.header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Connect request Content-Type %q", ct)
+			}
+			if !reflect.DeepEqual(next.body, tt.wantBody) {
+				t.Errorf("Connect request body %v, want %v", next.body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestTranscoderNotMatched(t *testing.T) {
+	for _, tt := range []struct{ method, target string }{
+		{http.MethodGet, "/v1/books"},
+		{http.MethodGet, "/v1/books/"},
+		{http.MethodGet, "/v1/books/moby/pages"},
+		{http.MethodDelete, "/v1/books/moby"},
+		{http.MethodPost, "/test.v1.BookService/GetBook"},
+	} {
+		tr, next := newTestTranscoder(t)
+		serve(tr, tt.method, tt.target, "")
+		if next.procedure != tt.target {
+			t.Errorf("%s %s: passed %q to the Connect handler, want the request as is", tt.method, tt.target, next.procedure)
+		}
+	}
+}
+
+func TestTranscoderInvalidRequest(t *testing.T) {
+	for _, tt := range []struct{ method, target, body string }{
+		{http.MethodGet, "/v1/books/moby?version=x", ""},
+		{http.MethodGet, "/v1/books/moby?unknown=1", ""},
+		{http.MethodPatch, "/v1/books/moby", `{"title": 1}`},
+		{http.MethodPost, "/v1/shelves/classics/books", `not json`},
+	} {
+		tr, next := newTestTranscoder(t)
+		rec := serve(tr, tt.method, tt.target, tt.body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: status %d, want 400", tt.method, tt.target, rec.Code)
+		}
+		var status rpcStatus
+		if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || status.Code != int32(connect.CodeInvalidArgument) {
+			t.Errorf("%s %s: body %s", tt.method, tt.target, rec.Body)
+		}
+		if next.procedure != "" {
+			t.Errorf("%s %s: called the Connect handler", tt.method, tt.target)
+		}
+	}
+}
+
+func TestTranscoderErrors(t *testing.T) {
+	codes := map[connect.Code]int{
+		connect.CodeCanceled:           499,
+		connect.CodeUnknown:            http.StatusInternalServerError,
+		connect.CodeInvalidArgument:    http.StatusBadRequest,
+		connect.CodeDeadlineExceeded:   http.StatusGatewayTimeout,
+		connect.CodeNotFound:           http.StatusNotFound,
+		connect.CodeAlreadyExists:      http.StatusConflict,
+		connect.CodePermissionDenied:   http.StatusForbidden,
+		connect.CodeResourceExhausted:  http.StatusTooManyRequests,
+		connect.CodeFailedPrecondition: http.StatusBadRequest,
+		connect.CodeAborted:            http.StatusConflict,
+		connect.CodeOutOfRange:         http.StatusBadRequest,
+		connect.CodeUnimplemented:      http.StatusNotImplemented,
+		connect.CodeInternal:           http.StatusInternalServerError,
+		connect.CodeUnavailable:        http.StatusServiceUnavailable,
+		connect.CodeDataLoss:           http.StatusInternalServerError,
+		connect.CodeUnauthenticated:    http.StatusUnauthorized,
+	}
+	for code, wantStatus := range codes {
+		tr, next := newTestTranscoder(t)
+		next.status = http.StatusBadGateway // Connect's own status is not used.
+		next.respBody = `{"code":"` + code.String() + `","message":"failed"}`
+		rec := serve(tr, http.MethodGet, "/v1/books/moby", "")
+		if rec.Code != wantStatus {
+			t.Errorf("%s: status %d, want %d", code, rec.Code, wantStatus)
+		}
+		var status rpcStatus
+		if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+		if status.Code != int32(code) || status.Message != "failed" || status.Details == nil {
+			t.Errorf("%s: body %s", code, rec.Body)
+		}
+	}
+
+	tr, next := newTestTranscoder(t)
+	next.status = http.StatusBadGateway
+	next.respBody = "upstream down\n"
+	rec := serve(tr, http.MethodGet, "/v1/books/moby", "")
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `"message":"upstream down"`) {
+		t.Errorf("non-JSON error: status %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestTranscoderResponseHeaders(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusNotFound} {
+		tr, next := newTestTranscoder(t)
+		next.status = status
+		next.respHeader = http.Header{
+			"X-Request-Id":     {"abc"},
+			"Trailer-Checksum": {"123"},
+			"Content-Type":     {"application/proto"},
+			"Content-Encoding": {"gzip"},
+		}
+		if status != http.StatusOK {
+			next.respBody = `{"code":"not_found","message":"no"}`
+		}
+		rec := serve(tr, http.MethodGet, "/v1/books/moby", "")
+		h := rec.Result().Header
+		if h.Get("X-Request-Id") != "abc" || h.Get("Trailer-Checksum") != "123" {
+			t.Errorf("%d: headers %v, want the Connect headers and trailers", status, h)
+		}
+		if h.Get("Content-Type") != "application/json" || h.Get("Content-Encoding") != "" {
+			t.Errorf("%d: headers %v, want the body headers of the REST response", status, h)
+		}
+	}
+}
+
+type emojiServer struct{}
+
+func (emojiServer) GetEmoji(ctx context.Context, req *connect.Request[emojiv1.GetEmojiRequest]) (*connect.Response[emojiv1.GetEmojiResponse], error) {
+	if req.Msg.GetShortName() != "star" {
+		return nil, connect.NewError(connect.CodeNotFound, errors.New("emoji not found"))
+	}
+	res := connect.NewResponse(&emojiv1.GetEmojiResponse{
+		Emoji: &emojiv1.Emoji{ShortName: "star", Emoji: "⭐"},
+	})
+	res.Header().Set("X-Emoji", "found")
+	res.Trailer().Set("Emoji-Count", "1")
+	return res, nil
+}
+
+func TestTranscoderConnectHandler(t *testing.T) {
+	_, handler := emojiv1connect.NewEmojiServiceHandler(emojiServer{})
+	sd := emojiv1.File_emoji_v1_emoji_proto.Services().ByName("EmojiService")
+	tr, err := NewTranscoder(sd, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"/v1/emojis/star", "/v1/emojis?short_name=star", "/v1/emojis?shortName=star"} {
+		rec := serve(tr, http.MethodGet, target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", target, rec.Code, rec.Body)
+		}
+		want := `{"emoji":{"shortName":"star","emoji":"⭐"}}`
+		if got := strings.Join(strings.Fields(rec.Body.String()), ""); got != want {
+			t.Errorf("%s: body %s, want %s", target, got, want)
+		}
+		if h := rec.Result().Header; h.Get("X-Emoji") != "found" || h.Get("Trailer-Emoji-Count") != "1" {
+			t.Errorf("%s: headers %v", target, h)
+		}
+	}
+	rec := serve(tr, http.MethodGet, "/v1/emojis/unknown", "")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"message":"emoji not found"`) {
+		t.Errorf("unknown emoji: status %d, body %s", rec.Code, rec.Body)
+	}
+}
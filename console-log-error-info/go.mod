@@ -1,7 +1,5 @@
 module github.com/syumai/workers-playground/console-log-error-info
 
-go 1.21.3
-
-toolchain go1.22.2
+go 1.23.0
 
 require github.com/syumai/workers v0.23.3
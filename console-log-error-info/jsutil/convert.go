@@ -0,0 +1,661 @@
+package jsutil
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall/js"
+	"time"
+)
+
+var (
+	ObjectClass      = js.Global().Get("Object")
+	ArrayClass       = js.Global().Get("Array")
+	DateClass        = js.Global().Get("Date")
+	Uint8ArrayClass  = js.Global().Get("Uint8Array")
+	ArrayBufferClass = js.Global().Get("ArrayBuffer")
+	BigIntFunc       = js.Global().Get("BigInt")
+)
+
+var (
+	jsValueType         = reflect.TypeOf(js.Value{})
+	jsFuncType          = reflect.TypeOf(js.Func{})
+	timeType            = reflect.TypeOf(time.Time{})
+	bigIntType          = reflect.TypeOf(big.Int{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// ToJS converts a Go value into a JS value.
+//
+// Structs become objects keyed by their `json` tags, with the same
+// "-", "omitempty" and field conflict rules as encoding/json. []byte becomes a Uint8Array,
+// time.Time a Date (in millisecond precision), *big.Int a BigInt,
+// and nil pointers, maps and slices null.
+// A js.Value is returned as is, so js.Undefined() can be used to produce undefined.
+// Unlike encoding/json, json.Marshaler and encoding.TextMarshaler are not called on values;
+// TextMarshaler is only used for map keys.
+// Integers beyond the safe range of JS numbers (±2^53-1) and cyclic values are reported as errors.
+func ToJS(v any) (js.Value, error) {
+	e := &encoder{seen: map[any]struct{}{}}
+	return e.encode(reflect.ValueOf(v), "$")
+}
+
+type encoder struct {
+	seen map[any]struct{}
+}
+
+func (e *encoder) encode(rv reflect.Value, path string) (js.Value, error) {
+	if !rv.IsValid() {
+		return js.Null(), nil
+	}
+	switch rv.Type() {
+	case jsValueType:
+		return rv.Interface().(js.Value), nil
+	case jsFuncType:
+		return rv.Interface().(js.Func).Value, nil
+	case timeType:
+		return DateClass.New(rv.Interface().(time.Time).UnixMilli()), nil
+	case bigIntType:
+		if rv.CanAddr() {
+			return BigIntFunc.Invoke(rv.Addr().Interface().(*big.Int).String()), nil
+		}
+		b := reflect.New(bigIntType)
+		b.Elem().Set(rv)
+		return BigIntFunc.Invoke(b.Interface().(*big.Int).String()), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return js.ValueOf(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := rv.Int()
+		if n > maxSafeInteger || n < -maxSafeInteger {
+			return js.Value{}, fmt.Errorf("jsutil: %d at %s cannot be represented exactly as a JS number; use *big.Int", n, path)
+		}
+		return js.ValueOf(n), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n := rv.Uint()
+		if n > maxSafeInteger {
+			return js.Value{}, fmt.Errorf("jsutil: %d at %s cannot be represented exactly as a JS number; use *big.Int", n, path)
+		}
+		return js.ValueOf(n), nil
+	case reflect.Float32, reflect.Float64:
+		return js.ValueOf(rv.Float()), nil
+	case reflect.String:
+		return js.ValueOf(rv.String()), nil
+	case reflect.Interface:
+		if rv.IsNil() {
+			return js.Null(), nil
+		}
+		return e.encode(rv.Elem(), path)
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return js.Null(), nil
+		}
+		return e.visit(rv, path, func() (js.Value, error) {
+			return e.encode(rv.Elem(), path)
+		})
+	case reflect.Slice:
+		if rv.IsNil() {
+			return js.Null(), nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := Uint8ArrayClass.New(rv.Len())
+			js.CopyBytesToJS(b, rv.Bytes())
+			return b, nil
+		}
+		return e.visit(rv, path, func() (js.Value, error) {
+			return e.encodeArray(rv, path)
+		})
+	case reflect.Array:
+		return e.encodeArray(rv, path)
+	case reflect.Map:
+		if rv.IsNil() {
+			return js.Null(), nil
+		}
+		return e.visit(rv, path, func() (js.Value, error) {
+			return e.encodeMap(rv, path)
+		})
+	case reflect.Struct:
+		return e.encodeStruct(rv, path)
+	}
+	return js.Value{}, fmt.Errorf("jsutil: cannot convert Go %s into JS at %s", rv.Type(), path)
+}
+
+// maxSafeInteger is Number.MAX_SAFE_INTEGER.
+const maxSafeInteger = 1<<53 - 1
+
+// visit encodes a reference value, reporting an error if it is already being encoded.
+func (e *encoder) visit(rv reflect.Value, path string, fn func() (js.Value, error)) (js.Value, error) {
+	key := struct {
+		ptr uintptr
+		len int
+		typ reflect.Type
+	}{rv.Pointer(), 0, rv.Type()}
+	if rv.Kind() == reflect.Slice {
+		key.len = rv.Len()
+	}
+	if _, ok := e.seen[key]; ok {
+		return js.Value{}, fmt.Errorf("jsutil: cycle detected at %s", path)
+	}
+	e.seen[key] = struct{}{}
+	defer delete(e.seen, key)
+	return fn()
+}
+
+func (e *encoder) encodeArray(rv reflect.Value, path string) (js.Value, error) {
+	arr := ArrayClass.New(rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		v, err := e.encode(rv.Index(i), path+"["+strconv.Itoa(i)+"]")
+		if err != nil {
+			return js.Value{}, err
+		}
+		arr.SetIndex(i, v)
+	}
+	return arr, nil
+}
+
+func (e *encoder) encodeMap(rv reflect.Value, path string) (js.Value, error) {
+	obj := ObjectClass.New()
+	iter := rv.MapRange()
+	for iter.Next() {
+		key, err := mapKeyString(iter.Key())
+		if err != nil {
+			return js.Value{}, fmt.Errorf("%w at %s", err, path)
+		}
+		v, err := e.encode(iter.Value(), path+"."+key)
+		if err != nil {
+			return js.Value{}, err
+		}
+		obj.Set(key, v)
+	}
+	return obj, nil
+}
+
+func (e *encoder) encodeStruct(rv reflect.Value, path string) (js.Value, error) {
+	obj := ObjectClass.New()
+	for _, f := range structFields(rv.Type()) {
+		fv, ok := fieldByIndex(rv, f.index, false)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		v, err := e.encode(fv, path+"."+f.name)
+		if err != nil {
+			return js.Value{}, err
+		}
+		obj.Set(f.name, v)
+	}
+	return obj, nil
+}
+
+// isEmptyValue reports whether v is empty for omitempty, as encoding/json does.
+// Structs are never empty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+func mapKeyString(k reflect.Value) (string, error) {
+	if k.Type().Implements(textMarshalerType) {
+		b, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	switch k.Kind() {
+	case reflect.String:
+		return k.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("jsutil: unsupported map key type %s", k.Type())
+}
+
+// field is a struct field visible to JS.
+type field struct {
+	name      string
+	index     []int
+	tagged    bool
+	omitEmpty bool
+}
+
+// structFields lists the fields of t following encoding/json naming rules.
+// Fields of embedded structs without a tag are promoted unless a shallower field has the same name.
+// Of fields with the same name at the same depth, a single tagged one wins; otherwise all of them are dropped.
+func structFields(t reflect.Type) []field {
+	fields := collectFields(t)
+	byName := map[string][]field{}
+	for _, f := range fields {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	visible := fields[:0]
+	for _, f := range fields {
+		if dominant, ok := dominantField(byName[f.name]); ok && slices.Equal(dominant.index, f.index) {
+			visible = append(visible, f)
+		}
+	}
+	return visible
+}
+
+// dominantField returns the field which wins among fields with the same name, if any.
+func dominantField(fields []field) (field, bool) {
+	depth := len(fields[0].index)
+	for _, f := range fields[1:] {
+		depth = min(depth, len(f.index))
+	}
+	var shallowest, tagged []field
+	for _, f := range fields {
+		if len(f.index) != depth {
+			continue
+		}
+		shallowest = append(shallowest, f)
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	switch {
+	case len(shallowest) == 1:
+		return shallowest[0], true
+	case len(tagged) == 1:
+		return tagged[0], true
+	}
+	return field{}, false
+}
+
+// collectFields lists the fields of t and its embedded structs, walking them
+// breadth-first like encoding/json. A struct type is only expanded at the
+// shallowest depth it is embedded at, so self-embedding types terminate.
+func collectFields(t reflect.Type) []field {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var fields []field
+	visited := map[reflect.Type]bool{}
+	for next := []embedded{{typ: t}}; len(next) > 0; {
+		current := next
+		next = nil
+		// Types are marked per depth, so that a type embedded twice at the same
+		// depth is expanded twice and its fields conflict as in encoding/json.
+		for _, e := range current {
+			visited[e.typ] = true
+		}
+		for _, e := range current {
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				index := append(slices.Clone(e.index), i)
+				ft := sf.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					if !visited[ft] {
+						next = append(next, embedded{typ: ft, index: index})
+					}
+					continue
+				}
+				if !sf.IsExported() {
+					continue
+				}
+				tagged := name != ""
+				if !tagged {
+					name = sf.Name
+				}
+				fields = append(fields, field{
+					name:      name,
+					index:     index,
+					tagged:    tagged,
+					omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+				})
+			}
+		}
+	}
+	// Keep the order of the fields in the source, as encoding/json does.
+	slices.SortFunc(fields, func(a, b field) int { return slices.Compare(a.index, b.index) })
+	return fields
+}
+
+// fieldByIndex returns the field at index, allocating nil embedded pointers when alloc is set.
+func fieldByIndex(rv reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// FromJS converts a JS value into the Go value pointed to by target,
+// following the rules of ToJS in reverse.
+//
+// undefined leaves the target untouched, while null sets pointers, maps,
+// slices and interfaces to nil. A target of type js.Value receives the raw value.
+// Cyclic JS objects are reported as errors.
+func FromJS(v js.Value, target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("jsutil: FromJS target must be a non-nil pointer, got %T", target)
+	}
+	d := &decoder{}
+	return d.decode(v, rv.Elem(), "$")
+}
+
+type decoder struct {
+	// stack holds the JS objects being decoded, for cycle detection.
+	stack []js.Value
+}
+
+func (d *decoder) decode(v js.Value, rv reflect.Value, path string) error {
+	if rv.Type() == jsValueType {
+		rv.Set(reflect.ValueOf(v))
+		return nil
+	}
+	if v.IsUndefined() {
+		return nil
+	}
+	if v.IsNull() {
+		switch rv.Kind() {
+		case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+			rv.SetZero()
+		}
+		return nil
+	}
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return d.decode(v, rv.Elem(), path)
+	}
+
+	switch rv.Type() {
+	case timeType:
+		t, err := decodeTime(v)
+		if err != nil {
+			return d.errorf(v, rv, path, err)
+		}
+		rv.Set(reflect.ValueOf(t))
+		return nil
+	case bigIntType:
+		b, ok := new(big.Int).SetString(js.Global().Get("String").Invoke(v).String(), 10)
+		if !ok {
+			return d.errorf(v, rv, path, nil)
+		}
+		rv.Set(reflect.ValueOf(b).Elem())
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		if jsType(v) != js.TypeObject {
+			break
+		}
+		for _, s := range d.stack {
+			if s.Equal(v) {
+				return fmt.Errorf("jsutil: cycle detected at %s", path)
+			}
+		}
+		d.stack = append(d.stack, v)
+		defer func() { d.stack = d.stack[:len(d.stack)-1] }()
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		if jsType(v) != js.TypeBoolean {
+			return d.errorf(v, rv, path, nil)
+		}
+		rv.SetBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := decodeInt(v)
+		if err != nil || rv.OverflowInt(n) {
+			return d.errorf(v, rv, path, err)
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := decodeInt(v)
+		if err != nil || n < 0 || rv.OverflowUint(uint64(n)) {
+			return d.errorf(v, rv, path, err)
+		}
+		rv.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		if jsType(v) != js.TypeNumber {
+			return d.errorf(v, rv, path, nil)
+		}
+		rv.SetFloat(v.Float())
+	case reflect.String:
+		if jsType(v) != js.TypeString {
+			return d.errorf(v, rv, path, nil)
+		}
+		rv.SetString(v.String())
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return d.errorf(v, rv, path, nil)
+		}
+		x, err := d.decodeAny(v, path)
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(&x).Elem())
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b, ok := decodeBytes(v)
+			if !ok {
+				return d.errorf(v, rv, path, nil)
+			}
+			rv.SetBytes(b)
+			return nil
+		}
+		if !ArrayClass.Call("isArray", v).Bool() {
+			return d.errorf(v, rv, path, nil)
+		}
+		n := v.Length()
+		rv.Set(reflect.MakeSlice(rv.Type(), n, n))
+		for i := 0; i < n; i++ {
+			if err := d.decode(v.Index(i), rv.Index(i), path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		if !ArrayClass.Call("isArray", v).Bool() || v.Length() != rv.Len() {
+			return d.errorf(v, rv, path, nil)
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := d.decode(v.Index(i), rv.Index(i), path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if jsType(v) != js.TypeObject {
+			return d.errorf(v, rv, path, nil)
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		keys := ObjectClass.Call("keys", v)
+		for i := 0; i < keys.Length(); i++ {
+			key := keys.Index(i).String()
+			kv, err := decodeMapKey(key, rv.Type().Key())
+			if err != nil {
+				return fmt.Errorf("%w at %s", err, path)
+			}
+			ev := reflect.New(rv.Type().Elem()).Elem()
+			if err := d.decode(v.Get(key), ev, path+"."+key); err != nil {
+				return err
+			}
+			rv.SetMapIndex(kv, ev)
+		}
+	case reflect.Struct:
+		if jsType(v) != js.TypeObject {
+			return d.errorf(v, rv, path, nil)
+		}
+		for _, f := range structFields(rv.Type()) {
+			fv := v.Get(f.name)
+			if fv.IsUndefined() {
+				continue
+			}
+			target, _ := fieldByIndex(rv, f.index, true)
+			if err := d.decode(fv, target, path+"."+f.name); err != nil {
+				return err
+			}
+		}
+	default:
+		return d.errorf(v, rv, path, nil)
+	}
+	return nil
+}
+
+// decodeAny converts v into the natural Go type for an empty interface.
+func (d *decoder) decodeAny(v js.Value, path string) (any, error) {
+	switch jsTypeOf(v) {
+	case "bigint":
+		var b big.Int
+		return &b, d.decode(v, reflect.ValueOf(&b).Elem(), path)
+	case "boolean":
+		return v.Bool(), nil
+	case "number":
+		return v.Float(), nil
+	case "string":
+		return v.String(), nil
+	case "symbol", "function":
+		return v, nil
+	}
+	switch {
+	case v.InstanceOf(DateClass):
+		return decodeTime(v)
+	case v.InstanceOf(Uint8ArrayClass), v.InstanceOf(ArrayBufferClass):
+		b, _ := decodeBytes(v)
+		return b, nil
+	case ArrayClass.Call("isArray", v).Bool():
+		var s []any
+		return s, d.decode(v, reflect.ValueOf(&s).Elem(), path)
+	}
+	var m map[string]any
+	return m, d.decode(v, reflect.ValueOf(&m).Elem(), path)
+}
+
+func (d *decoder) errorf(v js.Value, rv reflect.Value, path string, err error) error {
+	if err != nil {
+		return fmt.Errorf("jsutil: cannot convert JS %s into Go %s at %s: %w", jsTypeOf(v), rv.Type(), path, err)
+	}
+	return fmt.Errorf("jsutil: cannot convert JS %s into Go %s at %s", jsTypeOf(v), rv.Type(), path)
+}
+
+// typeBigInt is the js.Type reported by jsType for bigints.
+const typeBigInt js.Type = -1
+
+// jsType is js.Value.Type, except that it reports bigints as typeBigInt
+// instead of panicking on them.
+func jsType(v js.Value) (t js.Type) {
+	defer func() {
+		if recover() != nil {
+			t = typeBigInt
+		}
+	}()
+	return v.Type()
+}
+
+// jsTypeOf returns the typeof of v.
+func jsTypeOf(v js.Value) string {
+	if t := jsType(v); t != typeBigInt {
+		return t.String()
+	}
+	return "bigint"
+}
+
+func decodeInt(v js.Value) (int64, error) {
+	switch jsTypeOf(v) {
+	case "number":
+		f := v.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, fmt.Errorf("%v is not an integer", f)
+		}
+		return int64(f), nil
+	case "bigint":
+		return strconv.ParseInt(js.Global().Get("String").Invoke(v).String(), 10, 64)
+	}
+	return 0, fmt.Errorf("%s is not a number", jsTypeOf(v))
+}
+
+func decodeTime(v js.Value) (time.Time, error) {
+	switch {
+	case jsType(v) == js.TypeString:
+		return time.Parse(time.RFC3339Nano, v.String())
+	case jsType(v) == js.TypeNumber:
+		return time.UnixMilli(int64(v.Float())), nil
+	case v.InstanceOf(DateClass):
+		ms := v.Call("getTime").Float()
+		if math.IsNaN(ms) {
+			return time.Time{}, fmt.Errorf("invalid Date")
+		}
+		return time.UnixMilli(int64(ms)), nil
+	}
+	return time.Time{}, fmt.Errorf("%s is not a Date", jsTypeOf(v))
+}
+
+func decodeBytes(v js.Value) ([]byte, bool) {
+	if v.InstanceOf(ArrayBufferClass) {
+		v = Uint8ArrayClass.New(v)
+	}
+	if !v.InstanceOf(Uint8ArrayClass) {
+		return nil, false
+	}
+	b := make([]byte, v.Length())
+	js.CopyBytesToGo(b, v)
+	return b, true
+}
+
+func decodeMapKey(key string, t reflect.Type) (reflect.Value, error) {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		kv := reflect.New(t)
+		if err := kv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+			return reflect.Value{}, err
+		}
+		return kv.Elem(), nil
+	}
+	kv := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		kv.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		kv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(key, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		kv.SetUint(n)
+	default:
+		return reflect.Value{}, fmt.Errorf("jsutil: unsupported map key type %s", t)
+	}
+	return kv, nil
+}
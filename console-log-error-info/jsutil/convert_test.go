@@ -0,0 +1,338 @@
+package jsutil
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"math/big"
+	"reflect"
+	"slices"
+	"strings"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Label string `json:"label"`
+}
+
+type Embedded struct {
+	ID int `json:"id"`
+}
+
+type sample struct {
+	Embedded
+	Name     string            `json:"name"`
+	Tags     []string          `json:"tags"`
+	Attrs    map[string]int    `json:"attrs"`
+	Data     []byte            `json:"data"`
+	At       time.Time         `json:"at"`
+	Big      *big.Int          `json:"big"`
+	Inner    *inner            `json:"inner"`
+	Nested   map[string]*inner `json:"nested,omitempty"`
+	Optional string            `json:"optional,omitempty"`
+	Skipped  string            `json:"-"`
+	Untagged bool
+	hidden   int
+}
+
+func TestToJSFromJSRoundTrip(t *testing.T) {
+	big1, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	tests := map[string]struct {
+		in  any
+		out any // pointer to a zero value of the round-trip type
+	}{
+		"bool":    {in: true, out: new(bool)},
+		"int":     {in: -42, out: new(int)},
+		"uint8":   {in: uint8(255), out: new(uint8)},
+		"float":   {in: 1.5, out: new(float64)},
+		"string":  {in: "hello", out: new(string)},
+		"bytes":   {in: []byte{0, 1, 2, 255}, out: new([]byte)},
+		"time":    {in: time.UnixMilli(1700000000123).UTC(), out: new(time.Time)},
+		"big.Int": {in: big1, out: new(*big.Int)},
+		"slice":   {in: []int{1, 2, 3}, out: new([]int)},
+		"array":   {in: [2]string{"a", "b"}, out: new([2]string)},
+		"map":     {in: map[string]float64{"x": 1, "y": 2}, out: new(map[string]float64)},
+		"int map": {in: map[int]string{1: "one"}, out: new(map[int]string)},
+		"nil ptr": {in: (*inner)(nil), out: new(*inner)},
+		"struct": {
+			in: sample{
+				Embedded: Embedded{ID: 7},
+				Name:     "gopher",
+				Tags:     []string{"a", "b"},
+				Attrs:    map[string]int{"n": 1},
+				Data:     []byte("bytes"),
+				At:       time.UnixMilli(1700000000000).UTC(),
+				Big:      big.NewInt(-5),
+				Inner:    &inner{Label: "in"},
+				Nested:   map[string]*inner{"k": {Label: "v"}},
+				Untagged: true,
+			},
+			out: new(sample),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			v, err := ToJS(tt.in)
+			if err != nil {
+				t.Fatalf("ToJS: %v", err)
+			}
+			if err := FromJS(v, tt.out); err != nil {
+				t.Fatalf("FromJS: %v", err)
+			}
+			got := reflect.ValueOf(tt.out).Elem().Interface()
+			if gotTime, ok := got.(time.Time); ok {
+				got = gotTime.UTC()
+			}
+			if s, ok := got.(sample); ok {
+				s.At = s.At.UTC()
+				got = s
+			}
+			if !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("round trip mismatch:\ngot:  %#v\nwant: %#v", got, tt.in)
+			}
+		})
+	}
+}
+
+func TestToJS(t *testing.T) {
+	v, err := ToJS(sample{Skipped: "x", hidden: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"Skipped", "hidden", "optional", "nested"} {
+		if !v.Get(key).IsUndefined() {
+			t.Errorf("%s: want undefined, got %v", key, v.Get(key))
+		}
+	}
+	for key, want := range map[string]string{
+		"id":       "number",
+		"name":     "string",
+		"at":       "object",
+		"Untagged": "boolean",
+	} {
+		if got := jsTypeOf(v.Get(key)); got != want {
+			t.Errorf("%s: want %s, got %s", key, want, got)
+		}
+	}
+	if !v.Get("tags").IsNull() {
+		t.Errorf("nil slice: want null, got %v", v.Get("tags"))
+	}
+	if !v.Get("at").InstanceOf(DateClass) {
+		t.Errorf("time.Time: want Date, got %v", v.Get("at"))
+	}
+	if got, err := ToJS(js.Undefined()); err != nil || !got.IsUndefined() {
+		t.Errorf("js.Undefined: want undefined, got %v, %v", got, err)
+	}
+	b, _ := ToJS(big.NewInt(1))
+	if got := jsTypeOf(b); got != "bigint" {
+		t.Errorf("*big.Int: want bigint, got %s", got)
+	}
+}
+
+func TestFromJSNullAndUndefined(t *testing.T) {
+	s := sample{Name: "keep", Inner: &inner{Label: "drop"}}
+	obj := ObjectClass.New()
+	obj.Set("name", js.Undefined())
+	obj.Set("inner", js.Null())
+	if err := FromJS(obj, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "keep" {
+		t.Errorf("undefined must leave the field untouched, got %q", s.Name)
+	}
+	if s.Inner != nil {
+		t.Errorf("null must set the pointer to nil, got %#v", s.Inner)
+	}
+
+	var raw js.Value
+	if err := FromJS(js.Undefined(), &raw); err != nil || !raw.IsUndefined() {
+		t.Errorf("js.Value target: want undefined, got %v, %v", raw, err)
+	}
+}
+
+func TestFromJSAny(t *testing.T) {
+	obj := ObjectClass.New()
+	obj.Set("n", 1)
+	obj.Set("s", "str")
+	obj.Set("list", []any{true, nil})
+	var got any
+	if err := FromJS(obj, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"n": 1.0, "s": "str", "list": []any{true, nil}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+type node struct {
+	Next *node `json:"next"`
+}
+
+func TestCycles(t *testing.T) {
+	n := &node{}
+	n.Next = n
+	if _, err := ToJS(n); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("ToJS: want cycle error, got %v", err)
+	}
+
+	obj := ObjectClass.New()
+	obj.Set("next", obj)
+	var got node
+	if err := FromJS(obj, &got); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("FromJS: want cycle error, got %v", err)
+	}
+}
+
+func TestFromJSErrors(t *testing.T) {
+	tests := map[string]struct {
+		in     js.Value
+		target any
+	}{
+		"string into int":    {in: js.ValueOf("1"), target: new(int)},
+		"fraction into int":  {in: js.ValueOf(1.5), target: new(int)},
+		"overflow":           {in: js.ValueOf(256), target: new(uint8)},
+		"number into slice":  {in: js.ValueOf(1), target: new([]int)},
+		"bigint into string": {in: BigIntFunc.Invoke(1), target: new(string)},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := FromJS(tt.in, tt.target); err == nil {
+				t.Error("want error, got nil")
+			}
+		})
+	}
+	if err := FromJS(js.ValueOf(1), 0); err == nil {
+		t.Error("non-pointer target: want error, got nil")
+	}
+}
+
+type omitEmpty struct {
+	Zero   inner          `json:"zero,omitempty"`
+	At     time.Time      `json:"at,omitempty"`
+	Slice  []int          `json:"slice,omitempty"`
+	Map    map[string]int `json:"map,omitempty"`
+	Ptr    *inner         `json:"ptr,omitempty"`
+	Any    any            `json:"any,omitempty"`
+	Bool   bool           `json:"bool,omitempty"`
+	Uint   uint           `json:"uint,omitempty"`
+	Float  float64        `json:"float,omitempty"`
+	String string         `json:"string,omitempty"`
+	Array  [0]int         `json:"array,omitempty"`
+	Set    []int          `json:"set,omitempty"`
+}
+
+type named1 struct {
+	Name string
+	Both string
+}
+
+type named2 struct {
+	Name string
+	Both string `json:"Both"`
+}
+
+type named3 struct {
+	Both string `json:"Both"`
+}
+
+type conflicts struct {
+	named1
+	named2
+	Deep struct{ named3 }
+}
+
+type tagWins struct {
+	named1
+	named2
+}
+
+type shallowWins struct {
+	named1
+	Name int
+}
+
+// Node embeds itself, which must not make the field walk recurse forever.
+type Node struct {
+	*Node
+	V int `json:"v"`
+}
+
+type LoopA struct {
+	*LoopB
+	A int `json:"a"`
+}
+
+type LoopB struct {
+	*LoopA
+	B int `json:"b"`
+}
+
+// TestToJSMatchesEncodingJSON compares the keys of ToJS with those of encoding/json.
+func TestToJSMatchesEncodingJSON(t *testing.T) {
+	for name, in := range map[string]any{
+		"omitempty":        omitEmpty{Slice: []int{}, Map: map[string]int{}, Set: []int{1}},
+		"conflicts":        conflicts{},
+		"tag wins":         tagWins{named1{Name: "1", Both: "1"}, named2{Name: "2", Both: "2"}},
+		"shallow wins":     shallowWins{Name: 3},
+		"self embedding":   Node{Node: &Node{V: 2}, V: 1},
+		"mutual embedding": LoopA{LoopB: &LoopB{B: 2}, A: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			b, err := json.Marshal(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var m map[string]any
+			json.Unmarshal(b, &m)
+			want := slices.Sorted(maps.Keys(m))
+
+			v, err := ToJS(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			if err := FromJS(ObjectClass.Call("keys", v), &got); err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Errorf("ToJS: %v\nencoding/json: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFromJSSelfEmbedding(t *testing.T) {
+	v, err := ToJS(LoopA{LoopB: &LoopB{B: 2}, A: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got LoopA
+	if err := FromJS(v, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.A != 1 || got.LoopB == nil || got.B != 2 || got.LoopB.LoopA != nil {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestToJSUnsafeIntegers(t *testing.T) {
+	for _, in := range []any{int64(maxSafeInteger + 1), int64(-maxSafeInteger - 1), uint64(math.MaxUint64), []int{0, 1 << 60}} {
+		if _, err := ToJS(in); err == nil {
+			t.Errorf("ToJS(%v): want error, got nil", in)
+		}
+	}
+	for _, in := range []int64{maxSafeInteger, -maxSafeInteger} {
+		v, err := ToJS(in)
+		if err != nil {
+			t.Fatalf("ToJS(%d): %v", in, err)
+		}
+		var got int64
+		if err := FromJS(v, &got); err != nil || got != in {
+			t.Errorf("round trip of %d: got %d, %v", in, got, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package jsutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"syscall/js"
+)
+
+// maxCauseDepth bounds the cause chain so cyclic causes terminate.
+const maxCauseDepth = 32
+
+// JSError is a JS Error converted to a Go error.
+// Values thrown without being an Error keep their string form in Message.
+type JSError struct {
+	Name    string
+	Message string
+	Stack   string
+	Cause   error
+
+	value js.Value
+}
+
+var _ error = (*JSError)(nil)
+
+// NewJSError converts a thrown JS value into a *JSError.
+// It returns nil for undefined and null.
+func NewJSError(v js.Value) *JSError {
+	return newJSError(v, 0)
+}
+
+func newJSError(v js.Value, depth int) *JSError {
+	if v.IsUndefined() || v.IsNull() {
+		return nil
+	}
+	e := &JSError{value: v}
+	if jsType(v) != js.TypeObject || !(v.InstanceOf(ErrorClass) || jsType(v.Get("message")) == js.TypeString) {
+		e.Message = jsString(v)
+		return e
+	}
+	e.Name = stringProp(v, "name")
+	e.Message = stringProp(v, "message")
+	e.Stack = stringProp(v, "stack")
+	if cause := v.Get("cause"); depth < maxCauseDepth && !cause.IsUndefined() {
+		if c := newJSError(cause, depth+1); c != nil {
+			e.Cause = c
+		}
+	}
+	return e
+}
+
+func (e *JSError) Error() string {
+	switch {
+	case e.Name == "":
+		return e.Message
+	case e.Message == "":
+		return e.Name
+	}
+	return e.Name + ": " + e.Message
+}
+
+func (e *JSError) Unwrap() error {
+	return e.Cause
+}
+
+// Value returns the original JS value, or undefined if e was not created from JS.
+func (e *JSError) Value() js.Value {
+	return e.value
+}
+
+// Format prints the stack and cause chain with the %+v verb.
+func (e *JSError) Format(s fmt.State, verb rune) {
+	if verb != 'v' || !s.Flag('+') {
+		io.WriteString(s, e.Error())
+		return
+	}
+	if e.Stack != "" {
+		io.WriteString(s, e.Stack)
+	} else {
+		io.WriteString(s, e.Error())
+	}
+	if e.Cause != nil {
+		fmt.Fprintf(s, "\ncaused by: %+v", e.Cause)
+	}
+}
+
+// ErrorToJS converts a Go error into a JS Error.
+// The Error's stack holds the Go call stack, and wrapped errors become its cause.
+// A *JSError created by NewJSError is returned as the original value.
+func ErrorToJS(err error) js.Value {
+	return errorToJS(err, 0)
+}
+
+func errorToJS(err error, depth int) js.Value {
+	if err == nil {
+		return js.Null()
+	}
+	name := "Error"
+	if jsErr, ok := err.(*JSError); ok {
+		if !jsErr.value.IsUndefined() {
+			return jsErr.value
+		}
+		if jsErr.Name != "" {
+			name = jsErr.Name
+		}
+	}
+	v := ErrorClass.New(err.Error())
+	v.Set("name", name)
+	v.Set("stack", fmt.Sprintf("%s: %s\n%s", name, err.Error(), goStack(4)))
+	if cause := errors.Unwrap(err); cause != nil && depth < maxCauseDepth {
+		v.Set("cause", errorToJS(cause, depth+1))
+	}
+	return v
+}
+
+// goStack formats the Go call stack in the "    at fn (file:line)" form used by V8.
+func goStack(skip int) string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "    at %s (%s:%d)\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func stringProp(v js.Value, name string) string {
+	p := v.Get(name)
+	if jsType(p) != js.TypeString {
+		return ""
+	}
+	return p.String()
+}
+
+func jsString(v js.Value) string {
+	if jsType(v) == js.TypeString {
+		return v.String()
+	}
+	return js.Global().Get("String").Invoke(v).String()
+}
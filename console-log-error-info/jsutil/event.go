@@ -0,0 +1,143 @@
+package jsutil
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+	"syscall/js"
+)
+
+var (
+	AbortControllerClass = js.Global().Get("AbortController")
+
+	reflectObject       = js.Global().Get("Reflect")
+	symbolAsyncIterator = js.Global().Get("Symbol").Get("asyncIterator")
+	symbolIterator      = js.Global().Get("Symbol").Get("iterator")
+)
+
+// Iterate returns a sequence over the values of a JS async iterable, such as a ReadableStream.
+// Sync iterables and iterator objects are also accepted.
+// Iteration stops after yielding an error from the iterator or ctx, and
+// the iterator's return method is called when the loop exits early.
+func Iterate(ctx context.Context, v js.Value) iter.Seq2[js.Value, error] {
+	return func(yield func(js.Value, error) bool) {
+		it, err := getIterator(v)
+		if err != nil {
+			yield(js.Undefined(), err)
+			return
+		}
+		for {
+			result, err := AwaitPromiseContext(ctx, PromiseClass.Call("resolve", it.Call("next")))
+			if err != nil {
+				if ctx.Err() != nil {
+					closeIterator(it)
+				}
+				yield(js.Undefined(), err)
+				return
+			}
+			if result.Get("done").Truthy() {
+				return
+			}
+			if !yield(result.Get("value"), nil) {
+				closeIterator(it)
+				return
+			}
+		}
+	}
+}
+
+func getIterator(v js.Value) (js.Value, error) {
+	// Object(v) boxes primitives, which Reflect.get rejects.
+	obj := ObjectClass.Invoke(v)
+	for _, sym := range []js.Value{symbolAsyncIterator, symbolIterator} {
+		if fn := reflectObject.Call("get", obj, sym); jsType(fn) == js.TypeFunction {
+			return fn.Call("call", v), nil
+		}
+	}
+	if jsType(obj.Get("next")) == js.TypeFunction {
+		return v, nil
+	}
+	return js.Value{}, errors.New("jsutil: value is not iterable")
+}
+
+func closeIterator(it js.Value) {
+	if jsType(it.Get("return")) == js.TypeFunction {
+		PromiseClass.Call("resolve", it.Call("return")).Call("catch", noop)
+	}
+}
+
+// noop is a shared callback for promises whose result is ignored.
+var noop = js.FuncOf(func(js.Value, []js.Value) any {
+	return js.Undefined()
+})
+
+// On adds fn as a listener of event on target and returns a function removing it.
+// fn runs on the JS event loop, so it must not block; start a goroutine for blocking work.
+// The underlying js.Func is released on unsubscribe.
+func On(target js.Value, event string, fn func(ev js.Value)) (unsubscribe func()) {
+	cb := funcOf(func(_ js.Value, args []js.Value) any {
+		ev := js.Undefined()
+		if len(args) > 0 {
+			ev = args[0]
+		}
+		fn(ev)
+		return js.Undefined()
+	})
+	target.Call("addEventListener", event, cb)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			target.Call("removeEventListener", event, cb)
+			releaseFunc(cb)
+		})
+	}
+}
+
+// AbortSignalFromContext returns an AbortSignal aborted when ctx is done,
+// so a Go context can cancel a JS fetch.
+// The abort reason is a TimeoutError for an exceeded deadline, and an AbortError otherwise.
+func AbortSignalFromContext(ctx context.Context) js.Value {
+	controller := AbortControllerClass.New()
+	abort := func() {
+		reason := ErrorToJS(context.Cause(ctx))
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			reason.Set("name", "TimeoutError")
+		} else {
+			reason.Set("name", "AbortError")
+		}
+		controller.Call("abort", reason)
+	}
+	if ctx.Err() != nil {
+		abort()
+	} else {
+		context.AfterFunc(ctx, abort)
+	}
+	return controller.Get("signal")
+}
+
+// ContextFromAbortSignal returns a copy of parent canceled when signal is aborted,
+// so the JS side can cancel Go work such as that of PromiseFromGo.
+// The cause of the cancellation is the abort reason converted by NewJSError.
+// cancel must be called to release the event listener.
+func ContextFromAbortSignal(parent context.Context, signal js.Value) (ctx context.Context, cancel context.CancelFunc) {
+	ctx, cancelCause := context.WithCancelCause(parent)
+	if signal.Get("aborted").Bool() {
+		cancelCause(abortCause(signal))
+		return ctx, func() { cancelCause(context.Canceled) }
+	}
+	unsubscribe := On(signal, "abort", func(js.Value) {
+		cancelCause(abortCause(signal))
+	})
+	return ctx, func() {
+		unsubscribe()
+		cancelCause(context.Canceled)
+	}
+}
+
+func abortCause(signal js.Value) error {
+	if err := NewJSError(signal.Get("reason")); err != nil {
+		return err
+	}
+	return context.Canceled
+}
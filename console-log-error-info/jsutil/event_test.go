@@ -0,0 +1,132 @@
+package jsutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"syscall/js"
+	"testing"
+	"time"
+)
+
+func TestIterate(t *testing.T) {
+	w, stream := NewStreamWriter()
+	go func() {
+		for _, s := range []string{"a", "b", "c"} {
+			w.Write([]byte(s))
+		}
+		w.Close()
+	}()
+	var got string
+	for v, err := range Iterate(context.Background(), stream) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := decodeChunk(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got += b
+	}
+	if got != "abc" {
+		t.Errorf("got %q, want %q", got, "abc")
+	}
+}
+
+func decodeChunk(v js.Value) (string, error) {
+	var b []byte
+	err := FromJS(v, &b)
+	return string(b), err
+}
+
+func TestIterateContext(t *testing.T) {
+	_, stream := NewStreamWriter()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	for _, err := range Iterate(ctx, stream) {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want context.DeadlineExceeded, got %v", err)
+		}
+	}
+	for _, err := range Iterate(ctx, js.ValueOf(1)) {
+		if err == nil {
+			t.Error("non-iterable: want error, got nil")
+		}
+	}
+}
+
+func TestOn(t *testing.T) {
+	target := js.Global().Get("EventTarget").New()
+	var count int
+	unsubscribe := On(target, "ping", func(ev js.Value) {
+		if ev.Get("type").String() != "ping" {
+			t.Errorf("unexpected event %v", ev.Get("type"))
+		}
+		count++
+	})
+	ping := func() { target.Call("dispatchEvent", js.Global().Get("Event").New("ping")) }
+	ping()
+	ping()
+	unsubscribe()
+	unsubscribe()
+	ping()
+	if count != 2 {
+		t.Errorf("got %d events, want 2", count)
+	}
+}
+
+func TestAbortSignalFromContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signal := AbortSignalFromContext(ctx)
+	aborted := make(chan string, 1)
+	defer On(signal, "abort", func(js.Value) {
+		aborted <- signal.Get("reason").Get("name").String()
+	})()
+	if signal.Get("aborted").Bool() {
+		t.Fatal("signal aborted before cancel")
+	}
+	cancel()
+	select {
+	case name := <-aborted:
+		if name != "AbortError" {
+			t.Errorf("got reason %s, want AbortError", name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("signal not aborted")
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	signal = AbortSignalFromContext(ctx)
+	if !signal.Get("aborted").Bool() || signal.Get("reason").Get("name").String() != "TimeoutError" {
+		t.Errorf("expired context: want aborted with TimeoutError, got %v", signal.Get("reason"))
+	}
+}
+
+func TestContextFromAbortSignal(t *testing.T) {
+	controller := AbortControllerClass.New()
+	ctx, cancel := ContextFromAbortSignal(context.Background(), controller.Get("signal"))
+	defer cancel()
+
+	started := make(chan struct{})
+	p := PromiseFromGo(ctx, func(ctx context.Context) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, context.Cause(ctx)
+	})
+	<-started
+	controller.Call("abort", "gave up")
+	_, err := AwaitPromise(p)
+	if err == nil || !strings.Contains(err.Error(), "gave up") {
+		t.Errorf("want the abort reason, got %v", err)
+	}
+
+	aborted := AbortControllerClass.New()
+	aborted.Call("abort")
+	ctx, cancel = ContextFromAbortSignal(context.Background(), aborted.Get("signal"))
+	defer cancel()
+	var jsErr *JSError
+	if !errors.As(context.Cause(ctx), &jsErr) || jsErr.Name != "AbortError" {
+		t.Errorf("already aborted signal: cause %v, want AbortError", context.Cause(ctx))
+	}
+}
@@ -0,0 +1,26 @@
+package jsutil
+
+import (
+	"sync/atomic"
+	"syscall/js"
+)
+
+// liveFuncs counts js.Funcs created by jsutil helpers and not yet released.
+var liveFuncs atomic.Int64
+
+// LiveFuncs returns the number of js.Funcs created by jsutil helpers that are not released yet.
+// A count growing with the number of handled requests indicates a leak.
+func LiveFuncs() int64 {
+	return liveFuncs.Load()
+}
+
+// funcOf is js.FuncOf counted by LiveFuncs. The func must be released once with releaseFunc.
+func funcOf(fn func(this js.Value, args []js.Value) any) js.Func {
+	liveFuncs.Add(1)
+	return js.FuncOf(fn)
+}
+
+func releaseFunc(f js.Func) {
+	f.Release()
+	liveFuncs.Add(-1)
+}
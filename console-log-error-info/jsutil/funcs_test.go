@@ -0,0 +1,42 @@
+package jsutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"syscall/js"
+	"testing"
+)
+
+func TestLiveFuncsReturnToZero(t *testing.T) {
+	base := LiveFuncs()
+	for i := 0; i < 5000; i++ {
+		if _, err := AwaitPromise(PromiseClass.Call("resolve", i)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := AwaitPromise(PromiseClass.Call("reject", i)); err == nil {
+			t.Fatal("want error, got nil")
+		}
+		_, err := AwaitPromise(PromiseFromGo(context.Background(), func(context.Context) (any, error) {
+			return nil, errors.New("boom")
+		}))
+		if err == nil {
+			t.Fatal("want error, got nil")
+		}
+	}
+	for i := 0; i < 100; i++ {
+		w, stream := NewStreamWriter()
+		go func() {
+			w.Write([]byte("data"))
+			w.Close()
+		}()
+		if _, err := io.ReadAll(NewStreamReader(stream)); err != nil {
+			t.Fatal(err)
+		}
+		unsubscribe := On(js.Global().Get("EventTarget").New(), "ping", func(js.Value) {})
+		unsubscribe()
+	}
+	if got := LiveFuncs(); got != base {
+		t.Errorf("LiveFuncs: got %d, want %d", got, base)
+	}
+}
@@ -0,0 +1,206 @@
+//go:build js && wasm
+
+package jslog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"syscall/js"
+	"time"
+
+	"github.com/syumai/workers-playground/console-log-error-info/jsutil"
+)
+
+var (
+	console     = js.Global().Get("console")
+	objectClass = js.Global().Get("Object")
+	dateClass   = js.Global().Get("Date")
+)
+
+// groupOrAttrs holds either a group name or attrs added by WithGroup / WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+type handler struct {
+	opts slog.HandlerOptions
+	goas []groupOrAttrs
+}
+
+var _ slog.Handler = (*handler)(nil)
+
+// NewHandler returns a slog.Handler writing to the JS console.
+func NewHandler(opts *slog.HandlerOptions) slog.Handler {
+	h := &handler{}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *handler) Handle(_ context.Context, r slog.Record) error {
+	root := objectClass.New()
+	if !r.Time.IsZero() {
+		h.setAttr(root, nil, slog.Time(slog.TimeKey, r.Time))
+	}
+	if h.opts.AddSource && r.PC != 0 {
+		h.setAttr(root, nil, slog.Any(slog.SourceKey, source(r.PC)))
+	}
+
+	goas := h.goas
+	if r.NumAttrs() == 0 {
+		// Groups opened by WithGroup are omitted if no attrs follow them.
+		for len(goas) > 0 && goas[len(goas)-1].group != "" {
+			goas = goas[:len(goas)-1]
+		}
+	}
+	obj := root
+	var groups []string
+	for _, goa := range goas {
+		if goa.group != "" {
+			child := objectClass.New()
+			obj.Set(goa.group, child)
+			obj = child
+			groups = append(groups, goa.group)
+			continue
+		}
+		for _, a := range goa.attrs {
+			h.setAttr(obj, groups, a)
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		h.setAttr(obj, groups, a)
+		return true
+	})
+
+	console.Call(consoleMethod(r.Level), r.Message, root)
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.withGroupOrAttrs(groupOrAttrs{attrs: attrs})
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.withGroupOrAttrs(groupOrAttrs{group: name})
+}
+
+func (h *handler) withGroupOrAttrs(goa groupOrAttrs) *handler {
+	h2 := *h
+	h2.goas = make([]groupOrAttrs, len(h.goas)+1)
+	copy(h2.goas, h.goas)
+	h2.goas[len(h2.goas)-1] = goa
+	return &h2
+}
+
+func (h *handler) setAttr(obj js.Value, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if h.opts.ReplaceAttr != nil && a.Value.Kind() != slog.KindGroup {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		obj.Set(a.Key, toJSValue(a.Value))
+		return
+	}
+	attrs := a.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+	if a.Key == "" {
+		for _, ga := range attrs {
+			h.setAttr(obj, groups, ga)
+		}
+		return
+	}
+	child := objectClass.New()
+	for _, ga := range attrs {
+		h.setAttr(child, append(groups, a.Key), ga)
+	}
+	// A group whose attrs were all empty or removed by ReplaceAttr is omitted.
+	if objectClass.Call("keys", child).Length() > 0 {
+		obj.Set(a.Key, child)
+	}
+}
+
+// consoleMethod maps a slog level to a console method.
+func consoleMethod(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return "debug"
+	case level < slog.LevelWarn:
+		return "info"
+	case level < slog.LevelError:
+		return "warn"
+	}
+	return "error"
+}
+
+func toJSValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindTime:
+		return dateClass.New(float64(v.Time().UnixNano()) / float64(time.Millisecond))
+	}
+	return anyToJSValue(v.Any())
+}
+
+func anyToJSValue(v any) any {
+	switch v := v.(type) {
+	case nil:
+		return js.Null()
+	case js.Value:
+		return v
+	case error:
+		// A *jsutil.JSError is logged as its original value, so the console keeps the JS stack.
+		return jsutil.ErrorToJS(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	jv, err := jsutil.ToJS(v)
+	if err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return jv
+}
+
+func source(pc uintptr) *slog.Source {
+	frames := runtime.CallersFrames([]uintptr{pc})
+	f, _ := frames.Next()
+	return &slog.Source{
+		Function: f.Function,
+		File:     f.File,
+		Line:     f.Line,
+	}
+}
@@ -0,0 +1,106 @@
+//go:build js && wasm
+
+package jslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"syscall/js"
+	"testing"
+	"testing/slogtest"
+)
+
+// recordConsole replaces console with an object recording its calls until the test ends.
+// The returned func returns the calls as maps like those of slog.JSONHandler.
+func recordConsole(t *testing.T) func() []map[string]any {
+	t.Helper()
+	orig := console
+	var calls []map[string]any
+	rec := objectClass.New()
+	for method, level := range map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"info":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		fn := js.FuncOf(func(this js.Value, args []js.Value) any {
+			m := map[string]any{}
+			b := js.Global().Get("JSON").Call("stringify", args[1]).String()
+			if err := json.NewDecoder(bytes.NewReader([]byte(b))).Decode(&m); err != nil {
+				t.Errorf("console.%s: %v", method, err)
+			}
+			m[slog.LevelKey] = level.String()
+			m[slog.MessageKey] = args[0].String()
+			calls = append(calls, m)
+			return nil
+		})
+		t.Cleanup(fn.Release)
+		rec.Set(method, fn)
+	}
+	console = rec
+	t.Cleanup(func() { console = orig })
+	return func() []map[string]any { return calls }
+}
+
+func TestSlogtest(t *testing.T) {
+	var results func() []map[string]any
+	slogtest.Run(t, func(t *testing.T) slog.Handler {
+		results = recordConsole(t)
+		return NewHandler(nil)
+	}, func(t *testing.T) map[string]any {
+		calls := results()
+		if len(calls) != 1 {
+			t.Fatalf("%d console calls, want 1", len(calls))
+		}
+		return calls[0]
+	})
+}
+
+func TestEmptyGroups(t *testing.T) {
+	results := recordConsole(t)
+	logger := New(&slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "secret" || a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	logger.WithGroup("a").With("x", 1).WithGroup("b").WithGroup("c").Info("no attrs")
+	logger.Info("removed", slog.Group("g", "secret", "s"))
+	// slog drops the empty group from the record, so "a" has no attrs either.
+	logger.WithGroup("a").Info("empty group", slog.Group("g"))
+
+	want := []string{
+		`{"a":{"x":1},"level":"INFO","msg":"no attrs"}`,
+		`{"level":"INFO","msg":"removed"}`,
+		`{"level":"INFO","msg":"empty group"}`,
+	}
+	calls := results()
+	if len(calls) != len(want) {
+		t.Fatalf("%d console calls, want %d", len(calls), len(want))
+	}
+	for i, m := range calls {
+		b, _ := json.Marshal(m)
+		if string(b) != want[i] {
+			t.Errorf("call %d = %s, want %s", i, b, want[i])
+		}
+	}
+}
+
+func TestConsoleMethod(t *testing.T) {
+	results := recordConsole(t)
+	logger := New(&slog.HandlerOptions{Level: slog.LevelDebug})
+	ctx := context.Background()
+	for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError, slog.LevelError + 4} {
+		logger.Log(ctx, level, "m")
+	}
+	want := []string{"DEBUG", "INFO", "WARN", "ERROR", "ERROR"}
+	for i, m := range results() {
+		if m[slog.LevelKey] != want[i] {
+			t.Errorf("level %d: console method for %v, want %s", i, m[slog.LevelKey], want[i])
+		}
+	}
+}
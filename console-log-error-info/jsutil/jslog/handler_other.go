@@ -0,0 +1,13 @@
+//go:build !(js && wasm)
+
+package jslog
+
+import (
+	"log/slog"
+	"os"
+)
+
+// NewHandler returns a slog.Handler writing JSON to stderr.
+func NewHandler(opts *slog.HandlerOptions) slog.Handler {
+	return slog.NewJSONHandler(os.Stderr, opts)
+}
@@ -0,0 +1,14 @@
+// Package jslog provides a log/slog handler writing to the Workers console.
+//
+// On js/wasm, records are passed to console.debug, console.info, console.warn
+// and console.error with their attributes and time as a JS object, so Workers Logs and
+// `wrangler tail` show them as structured fields.
+// On other platforms, records are written to stderr as JSON.
+package jslog
+
+import "log/slog"
+
+// New returns a logger using NewHandler.
+func New(opts *slog.HandlerOptions) *slog.Logger {
+	return slog.New(NewHandler(opts))
+}
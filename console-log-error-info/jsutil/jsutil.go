@@ -1,37 +1,111 @@
+// Package jsutil converts values and errors between Go and JS, and bridges
+// promises, streams and events of the JS runtime.
+//
+// ToJS and FromJS follow the naming rules of encoding/json for structs, but
+// they convert values directly without calling json.Marshaler,
+// json.Unmarshaler, encoding.TextMarshaler or encoding.TextUnmarshaler,
+// except for the keys of maps. Types needing custom JSON should be converted
+// with encoding/json and JSON.parse instead.
 package jsutil
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"syscall/js"
 )
 
-var PromiseClass = js.Global().Get("Promise")
+var (
+	PromiseClass = js.Global().Get("Promise")
+	ErrorClass   = js.Global().Get("Error")
+)
 
 func NewPromise(fn js.Func) js.Value {
 	return PromiseClass.New(fn)
 }
 
+// promiseResult is the settled state of a promise.
+type promiseResult struct {
+	value js.Value
+	err   error
+}
+
+// AwaitPromise waits until promiseVal settles.
+// It blocks forever if the promise never settles; use AwaitPromiseContext to give up.
 func AwaitPromise(promiseVal js.Value) (js.Value, error) {
-	resultCh := make(chan js.Value)
-	errCh := make(chan error)
+	return AwaitPromiseContext(context.Background(), promiseVal)
+}
+
+// AwaitPromiseContext waits until promiseVal settles or ctx is done.
+// The result channel is buffered, so a promise settling after ctx is done
+// does not block the JS event loop. The callbacks registered on the promise
+// are released once it settles, even if ctx is done before that.
+func AwaitPromiseContext(ctx context.Context, promiseVal js.Value) (js.Value, error) {
+	resultCh := make(chan promiseResult, 1)
 	var then, catch js.Func
-	then = js.FuncOf(func(_ js.Value, args []js.Value) any {
-		defer then.Release()
-		result := args[0]
-		resultCh <- result
+	// Only one of then and catch is called, so both are released when the promise settles.
+	release := func() {
+		releaseFunc(then)
+		releaseFunc(catch)
+	}
+	then = funcOf(func(_ js.Value, args []js.Value) any {
+		defer release()
+		resultCh <- promiseResult{value: args[0]}
 		return js.Undefined()
 	})
-	catch = js.FuncOf(func(_ js.Value, args []js.Value) any {
-		defer catch.Release()
-		result := args[0]
-		errCh <- fmt.Errorf("failed on promise: %s", result.Call("toString").String())
+	catch = funcOf(func(_ js.Value, args []js.Value) any {
+		defer release()
+		var err error = errors.New("promise rejected with " + jsString(args[0]))
+		if jsErr := NewJSError(args[0]); jsErr != nil {
+			err = jsErr
+		}
+		resultCh <- promiseResult{err: fmt.Errorf("failed on promise: %w", err)}
 		return js.Undefined()
 	})
-	promiseVal.Call("then", then).Call("catch", catch)
+	promiseVal.Call("then", then, catch)
 	select {
 	case result := <-resultCh:
-		return result, nil
-	case err := <-errCh:
-		return js.Value{}, err
+		return result.value, result.err
+	case <-ctx.Done():
+		return js.Value{}, ctx.Err()
 	}
 }
+
+// Await waits until v settles and decodes the fulfilled value.
+// v may be a promise, a thenable or a plain value.
+func Await[T any](ctx context.Context, v js.Value, decode func(js.Value) (T, error)) (T, error) {
+	var zero T
+	result, err := AwaitPromiseContext(ctx, PromiseClass.Call("resolve", v))
+	if err != nil {
+		return zero, err
+	}
+	return decode(result)
+}
+
+// PromiseFromGo runs fn with ctx in a goroutine and returns a Promise settled with its result.
+// The promise is rejected with an Error converted by ErrorToJS when fn returns an error or panics.
+//
+// A JS Promise cannot be canceled, so ctx is the only way to stop fn when the JS side gives up.
+// Pass the context of the request, or one from ContextFromAbortSignal.
+func PromiseFromGo(ctx context.Context, fn func(ctx context.Context) (any, error)) js.Value {
+	var executor js.Func
+	executor = funcOf(func(_ js.Value, args []js.Value) any {
+		defer releaseFunc(executor)
+		resolve, reject := args[0], args[1]
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					reject.Invoke(ErrorToJS(fmt.Errorf("panic: %v", r)))
+				}
+			}()
+			v, err := fn(ctx)
+			if err != nil {
+				reject.Invoke(ErrorToJS(err))
+				return
+			}
+			resolve.Invoke(v)
+		}()
+		return js.Undefined()
+	})
+	return NewPromise(executor)
+}
@@ -0,0 +1,181 @@
+package jsutil
+
+import (
+	"context"
+	"io"
+	"sync"
+	"syscall/js"
+)
+
+var ReadableStreamClass = js.Global().Get("ReadableStream")
+
+// streamReader is an io.ReadCloser reading from a ReadableStream.
+type streamReader struct {
+	reader js.Value
+	chunk  js.Value // Uint8Array not yet consumed by Read
+	offset int
+	err    error
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+var _ io.ReadCloser = (*streamReader)(nil)
+
+// NewStreamReader returns an io.ReadCloser over a ReadableStream of Uint8Array chunks.
+// A chunk is pulled from the stream only when the previous one is fully read,
+// so the stream's backpressure is preserved. Close cancels the stream and
+// unblocks a pending Read.
+func NewStreamReader(stream js.Value) io.ReadCloser {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &streamReader{
+		reader: stream.Call("getReader"),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for r.chunk.IsUndefined() || r.offset >= r.chunk.Length() {
+		if r.err != nil {
+			return 0, r.err
+		}
+		result, err := AwaitPromiseContext(r.ctx, r.reader.Call("read"))
+		if err != nil {
+			if r.ctx.Err() != nil {
+				err = io.ErrClosedPipe
+			}
+			r.err = err
+			return 0, err
+		}
+		if result.Get("done").Bool() {
+			r.err = io.EOF
+			return 0, io.EOF
+		}
+		r.chunk, r.offset = result.Get("value"), 0
+	}
+	end := min(r.offset+len(p), r.chunk.Length())
+	n := js.CopyBytesToGo(p, r.chunk.Call("subarray", r.offset, end))
+	r.offset += n
+	return n, nil
+}
+
+func (r *streamReader) Close() error {
+	r.closeOnce.Do(func() {
+		r.cancel()
+		r.reader.Call("cancel")
+		r.reader.Call("releaseLock")
+	})
+	return nil
+}
+
+// streamWriter is an io.WriteCloser feeding a ReadableStream.
+type streamWriter struct {
+	chunks chan []byte
+	copied chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{} // closed by Close or CloseWithError
+	closeErr  error
+
+	cancelOnce sync.Once
+	canceled   chan struct{} // closed when the JS side cancels the stream
+
+	releaseOnce  sync.Once
+	pull, cancel js.Func
+}
+
+var _ io.WriteCloser = (*streamWriter)(nil)
+
+// NewStreamWriter returns an io.WriteCloser and the ReadableStream it feeds.
+// Each Write blocks until the stream pulls the bytes, so a slow consumer
+// slows down the writer. Writes fail with io.ErrClosedPipe once the consumer
+// cancels the stream.
+func NewStreamWriter() (io.WriteCloser, js.Value) {
+	w := &streamWriter{
+		chunks:   make(chan []byte),
+		copied:   make(chan struct{}),
+		closed:   make(chan struct{}),
+		canceled: make(chan struct{}),
+	}
+	w.pull = funcOf(func(_ js.Value, args []js.Value) any {
+		controller := args[0]
+		// pullChunk returns when the stream is canceled, so no context is needed.
+		return PromiseFromGo(context.Background(), func(ctx context.Context) (any, error) {
+			w.pullChunk(controller)
+			return nil, nil
+		})
+	})
+	w.cancel = funcOf(func(js.Value, []js.Value) any {
+		w.cancelOnce.Do(func() { close(w.canceled) })
+		go w.release()
+		return js.Undefined()
+	})
+	source := ObjectClass.New()
+	source.Set("pull", w.pull)
+	source.Set("cancel", w.cancel)
+	strategy := ObjectClass.New()
+	strategy.Set("highWaterMark", 0)
+	return w, ReadableStreamClass.New(source, strategy)
+}
+
+// pullChunk waits for a Write or Close and reflects it on controller.
+func (w *streamWriter) pullChunk(controller js.Value) {
+	select {
+	case p := <-w.chunks:
+		b := Uint8ArrayClass.New(len(p))
+		js.CopyBytesToJS(b, p)
+		w.copied <- struct{}{}
+		controller.Call("enqueue", b)
+	case <-w.closed:
+		if w.closeErr != nil {
+			controller.Call("error", ErrorToJS(w.closeErr))
+		} else {
+			controller.Call("close")
+		}
+		w.release()
+	case <-w.canceled:
+	}
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	select {
+	case w.chunks <- p:
+		<-w.copied
+		return len(p), nil
+	case <-w.closed:
+		return 0, io.ErrClosedPipe
+	case <-w.canceled:
+		return 0, io.ErrClosedPipe
+	}
+}
+
+// Close closes the stream once the consumer has read all written bytes.
+func (w *streamWriter) Close() error {
+	return w.CloseWithError(nil)
+}
+
+// CloseWithError errors the stream with err, or closes it if err is nil.
+func (w *streamWriter) CloseWithError(err error) error {
+	w.closeOnce.Do(func() {
+		w.closeErr = err
+		close(w.closed)
+	})
+	return nil
+}
+
+// release frees the underlying source callbacks once the stream is finished.
+// The stream never calls them again after it is closed, errored or canceled.
+func (w *streamWriter) release() {
+	w.releaseOnce.Do(func() {
+		releaseFunc(w.pull)
+		releaseFunc(w.cancel)
+	})
+}
@@ -0,0 +1,94 @@
+package jsutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestStreamRoundTrip(t *testing.T) {
+	want := bytes.Repeat([]byte("0123456789abcdef"), 10000)
+	w, stream := NewStreamWriter()
+	go func() {
+		for p := want; len(p) > 0; {
+			n := min(len(p), 4096)
+			if _, err := w.Write(p[:n]); err != nil {
+				t.Error(err)
+				return
+			}
+			p = p[n:]
+		}
+		w.Close()
+	}()
+	r := NewStreamReader(stream)
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %d bytes, want %d bytes", len(got), len(want))
+	}
+}
+
+func TestStreamWriterCloseWithError(t *testing.T) {
+	w, stream := NewStreamWriter()
+	go w.(interface{ CloseWithError(error) error }).CloseWithError(errors.New("boom"))
+	_, err := io.ReadAll(NewStreamReader(stream))
+	var jsErr *JSError
+	if !errors.As(err, &jsErr) || jsErr.Message != "boom" {
+		t.Errorf("want JSError boom, got %v", err)
+	}
+}
+
+func TestStreamReaderCloseCancelsWriter(t *testing.T) {
+	w, stream := NewStreamWriter()
+	r := NewStreamReader(stream)
+	done := make(chan error)
+	go func() {
+		for {
+			if _, err := w.Write([]byte("data")); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if err := <-done; !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("want io.ErrClosedPipe, got %v", err)
+	}
+	if _, err := r.Read(buf); err == nil {
+		t.Error("Read after Close: want error, got nil")
+	}
+}
+
+func BenchmarkStream(b *testing.B) {
+	const total = 1 << 20
+	for _, size := range []int{1 << 10, 16 << 10, 64 << 10, 256 << 10} {
+		b.Run(fmt.Sprintf("chunk=%dKiB", size>>10), func(b *testing.B) {
+			chunk := make([]byte, size)
+			buf := make([]byte, size)
+			b.SetBytes(total)
+			for i := 0; i < b.N; i++ {
+				w, stream := NewStreamWriter()
+				go func() {
+					for n := 0; n < total; n += size {
+						w.Write(chunk)
+					}
+					w.Close()
+				}()
+				r := NewStreamReader(stream)
+				if _, err := io.CopyBuffer(io.Discard, r, buf); err != nil {
+					b.Fatal(err)
+				}
+				r.Close()
+			}
+		})
+	}
+}
@@ -2,19 +2,29 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 	"syscall/js"
 
 	"github.com/syumai/workers"
+	"github.com/syumai/workers-playground/console-log-error-info/jsutil"
+	"github.com/syumai/workers-playground/console-log-error-info/jsutil/jslog"
 )
 
 func main() {
+	slog.SetDefault(jslog.New(nil))
 	http.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
 		v := js.Global().Get("Error").New("error")
 		fmt.Printf("err in printf: %#v\n", v)
-		js.Global().Get("console").Call("log", "err in console: ", v)
+		fmt.Printf("err as JSError: %+v\n", jsutil.NewJSError(v))
+		slog.Error("err in console", "err", v)
+		slog.Error("Go err in console", "err", fmt.Errorf("wrapped: %w", jsutil.NewJSError(v)), "path", req.URL.Path)
 		msg := "Hello!"
 		w.Write([]byte(msg))
 	})
+	http.HandleFunc("/debug/jsfuncs", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"liveFuncs":%d}`+"\n", jsutil.LiveFuncs())
+	})
 	workers.Serve(nil) // use http.DefaultServeMux
 }
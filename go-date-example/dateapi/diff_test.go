@@ -0,0 +1,35 @@
+package dateapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalendarDiff(t *testing.T) {
+	date := func(y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, time.UTC)
+	}
+	est, edt := time.FixedZone("EST", -5*3600), time.FixedZone("EDT", -4*3600)
+	tests := []struct {
+		name         string
+		a, b         time.Time
+		want         Calendar
+		wantNegative bool
+	}{
+		{"same", date(2024, 1, 1, 0, 0), date(2024, 1, 1, 0, 0), Calendar{}, false},
+		{"clock", date(2024, 1, 1, 22, 57), date(2024, 1, 3, 1, 0), Calendar{Days: 1, Hours: 2, Minutes: 3}, false},
+		{"end of month", date(2025, 1, 31, 0, 0), date(2025, 2, 28, 0, 0), Calendar{Months: 1}, false},
+		{"after end of month", date(2025, 1, 31, 0, 0), date(2025, 3, 1, 0, 0), Calendar{Months: 1, Days: 1}, false},
+		{"leap day", date(2024, 2, 29, 0, 0), date(2025, 2, 28, 0, 0), Calendar{Years: 1}, false},
+		{"years", date(2000, 5, 10, 12, 0), date(2024, 8, 1, 6, 30), Calendar{Years: 24, Months: 2, Days: 21, Hours: 18, Minutes: 30}, false},
+		{"negative", date(2024, 3, 1, 0, 0), date(2024, 1, 1, 0, 0), Calendar{Months: 2}, true},
+		// 23 hours elapse, but the wall clock advances a day.
+		{"dst", time.Date(2025, 3, 8, 12, 0, 0, 0, est), time.Date(2025, 3, 9, 12, 0, 0, 0, edt), Calendar{Days: 1}, false},
+	}
+	for _, tt := range tests {
+		got, negative := CalendarDiff(tt.a, tt.b)
+		if got != tt.want || negative != tt.wantNegative {
+			t.Errorf("%s: CalendarDiff = %+v, %v; want %+v, %v", tt.name, got, negative, tt.want, tt.wantNegative)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package dateapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 2, 29, 23, 45, 50, 123456789, time.FixedZone("JST", 9*3600))
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "2024-02-29T23:45:50+09:00"},
+		{"rfc3339", "2024-02-29T23:45:50+09:00"},
+		{"RFC3339Nano", "2024-02-29T23:45:50.123456789+09:00"},
+		{"rfc1123", "Thu, 29 Feb 2024 23:45:50 JST"},
+		{"rfc1123z", "Thu, 29 Feb 2024 23:45:50 +0900"},
+		{"unix", "1709217950"},
+		{"unixmilli", "1709217950123"},
+		{"unixnano", "1709217950123456789"},
+		{"datetime", "2024-02-29 23:45:50"},
+		{"2006/01/02 15:04 -07:00", "2024/02/29 23:45 +09:00"},
+	}
+	for _, tt := range tests {
+		f, err := ParseFormat(tt.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := f.Format(ts)
+		if got != tt.want {
+			t.Errorf("%q: Format = %q, want %q", tt.format, got, tt.want)
+		}
+		// datetime has no zone, so it is parsed in the zone of ts.
+		parsed, err := f.Parse(got, ts.Location())
+		if err != nil {
+			t.Errorf("%q: Parse: %v", tt.format, err)
+			continue
+		}
+		if f.Format(parsed.In(ts.Location())) != got {
+			t.Errorf("%q: Parse(%q) = %s", tt.format, got, parsed)
+		}
+	}
+}
+
+func TestParseFormatError(t *testing.T) {
+	for _, s := range []string{"iso", "foo bar"} {
+		if _, err := ParseFormat(s); !errors.Is(err, ErrFormat) {
+			t.Errorf("ParseFormat(%q): err = %v, want ErrFormat", s, err)
+		}
+	}
+	for format, value := range map[string]string{"unix": "12.5", "rfc3339": "2024-02-30T00:00:00Z"} {
+		f, _ := ParseFormat(format)
+		if _, err := f.Parse(value, time.UTC); !errors.Is(err, ErrFormat) {
+			t.Errorf("%s: Parse(%q): err = %v, want ErrFormat", format, value, err)
+		}
+	}
+}
+
+// TestParseUnixFarFuture checks times beyond the range of time.Duration since the epoch.
+func TestParseUnixFarFuture(t *testing.T) {
+	for format, value := range map[string]string{
+		"unix":      "99999999999",
+		"unixmilli": "99999999999000",
+		"unixmicro": "99999999999000000",
+	} {
+		f, _ := ParseFormat(format)
+		got, err := f.Parse(value, time.UTC)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if want := time.Date(5138, 11, 16, 9, 46, 39, 0, time.UTC); !got.Equal(want) {
+			t.Errorf("%s: Parse(%q) = %s, want %s", format, value, got, want)
+		}
+		if s := f.Format(got); s != value {
+			t.Errorf("%s: Format = %q, want %q", format, s, value)
+		}
+	}
+}
@@ -1,15 +1,15 @@
 package main
 
 import (
-	"net/http"
-	"time"
-
 	"github.com/syumai/workers"
+
+	"github.com/syumai/workers-playground/go-date-example/dateapi"
+	"github.com/syumai/workers-playground/go-date-example/tz"
 )
 
 func main() {
-	http.HandleFunc("/now", func(w http.ResponseWriter, req *http.Request) {
-		w.Write([]byte(time.Now().String()))
-	})
-	workers.Serve(nil) // use http.DefaultServeMux
+	workers.Serve(dateapi.New(dateapi.Options{
+		// Intl is a fallback for zones newer than the embedded database.
+		Sources: tz.Chain{tz.TZData{}, tz.Intl{}},
+	}))
 }
@@ -0,0 +1,18 @@
+package tz
+
+import "fmt"
+
+// numericAbbr returns the abbreviation of a zone without a common one,
+// in the style of the time zone database: "+09", "-0330".
+func numericAbbr(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	h, m := offset/3600, offset%3600/60
+	if m == 0 {
+		return fmt.Sprintf("%c%02d", sign, h)
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, h, m)
+}
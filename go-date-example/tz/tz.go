@@ -0,0 +1,61 @@
+// Package tz resolves IANA time zone names such as "Asia/Tokyo" on Wasm,
+// where the runtime has no zoneinfo files.
+//
+// Zones are resolved from the time zone database embedded by time/tzdata,
+// or, when running on a JavaScript runtime, from the Intl.DateTimeFormat API.
+package tz
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnknownZone is returned when a source does not know the zone.
+var ErrUnknownZone = errors.New("tz: unknown time zone")
+
+// Source resolves time zones.
+type Source interface {
+	// Name is the name of the source, such as "tzdata".
+	Name() string
+	// Zone returns the location of name which is valid at least at t.
+	// It returns an error wrapping ErrUnknownZone if name is not known.
+	Zone(name string, t time.Time) (*time.Location, error)
+}
+
+// Chain is a Source trying each source in order until one knows the zone.
+type Chain []Source
+
+func (c Chain) Name() string {
+	return "chain"
+}
+
+func (c Chain) Zone(name string, t time.Time) (*time.Location, error) {
+	loc, _, err := c.Resolve(name, t)
+	return loc, err
+}
+
+// Resolve is Zone also returning the source which resolved the zone.
+func (c Chain) Resolve(name string, t time.Time) (*time.Location, Source, error) {
+	for _, s := range c {
+		loc, err := s.Zone(name, t)
+		if errors.Is(err, ErrUnknownZone) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return loc, s, nil
+	}
+	return nil, nil, fmt.Errorf("%w: %q", ErrUnknownZone, name)
+}
+
+// Lookup returns the source of sources named name.
+func Lookup(sources []Source, name string) (Source, bool) {
+	for _, s := range sources {
+		if s.Name() == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
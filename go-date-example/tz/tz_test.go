@@ -0,0 +1,68 @@
+package tz
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTZData(t *testing.T) {
+	loc, err := TZData{}.Zone("Asia/Tokyo", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).In(loc)
+	if name, offset := ts.Zone(); name != "JST" || offset != 9*3600 {
+		t.Errorf("zone = %s %d", name, offset)
+	}
+	for _, name := range []string{"", "Local", "Mars/Olympus_Mons"} {
+		if _, err := (TZData{}).Zone(name, time.Time{}); !errors.Is(err, ErrUnknownZone) {
+			t.Errorf("%q: err = %v, want ErrUnknownZone", name, err)
+		}
+	}
+}
+
+// fixedSource knows a single zone.
+type fixedSource struct {
+	name, zone string
+	err        error
+}
+
+func (s fixedSource) Name() string { return s.name }
+
+func (s fixedSource) Zone(name string, _ time.Time) (*time.Location, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if name != s.zone {
+		return nil, ErrUnknownZone
+	}
+	return time.FixedZone(s.name, 0), nil
+}
+
+func TestChain(t *testing.T) {
+	c := Chain{fixedSource{name: "a", zone: "A"}, fixedSource{name: "b", zone: "B"}}
+	_, s, err := c.Resolve("B", time.Time{})
+	if err != nil || s.Name() != "b" {
+		t.Errorf("Resolve(B) = %v, %v", s, err)
+	}
+	if _, _, err := c.Resolve("C", time.Time{}); !errors.Is(err, ErrUnknownZone) {
+		t.Errorf("Resolve(C) err = %v", err)
+	}
+	broken := errors.New("broken")
+	c = Chain{fixedSource{err: broken}, fixedSource{name: "b", zone: "B"}}
+	if _, _, err := c.Resolve("B", time.Time{}); !errors.Is(err, broken) {
+		t.Errorf("errors other than ErrUnknownZone must stop the chain: %v", err)
+	}
+	if s, ok := Lookup(c, "b"); !ok || s.Name() != "b" {
+		t.Errorf("Lookup(b) = %v, %v", s, ok)
+	}
+}
+
+func TestNumericAbbr(t *testing.T) {
+	for offset, want := range map[int]string{0: "+00", 9 * 3600: "+09", -3 * 3600: "-03", 5*3600 + 45*60: "+0545", -(9*3600 + 30*60): "-0930"} {
+		if got := numericAbbr(offset); got != want {
+			t.Errorf("numericAbbr(%d) = %q, want %q", offset, got, want)
+		}
+	}
+}
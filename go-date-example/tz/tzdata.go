@@ -0,0 +1,27 @@
+package tz
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // embed the time zone database, since Wasm runtimes have no zoneinfo files
+)
+
+// TZData is a Source of the time zone database embedded by time/tzdata.
+// Its locations are valid at any time.
+type TZData struct{}
+
+func (TZData) Name() string {
+	return "tzdata"
+}
+
+func (TZData) Zone(name string, _ time.Time) (*time.Location, error) {
+	// LoadLocation accepts "" and "Local", which are not zone names.
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownZone, name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownZone, err)
+	}
+	return loc, nil
+}
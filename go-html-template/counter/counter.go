@@ -0,0 +1,43 @@
+// Package counter is the storage of the counter app.
+package counter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Counter is the state of the counter.
+type Counter struct {
+	Value int `json:"value"`
+	// UpdatedBy is the name given by the last person who changed the counter.
+	UpdatedBy string    `json:"updatedBy,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+}
+
+// Store stores the counter.
+type Store interface {
+	// Get returns the counter. A counter which is never stored has the zero value.
+	Get(ctx context.Context) (Counter, error)
+	// Put stores the counter.
+	Put(ctx context.Context, c Counter) error
+}
+
+// MemoryStore is a Store in memory.
+type MemoryStore struct {
+	mu sync.Mutex
+	c  Counter
+}
+
+func (s *MemoryStore) Get(context.Context) (Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c, nil
+}
+
+func (s *MemoryStore) Put(_ context.Context, c Counter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c = c
+	return nil
+}
@@ -0,0 +1,68 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/syumai/workers/cloudflare"
+
+	"github.com/syumai/workers-playground/go-html-template/counter"
+)
+
+// kvStore is a counter.Store keeping the counter as JSON in a KV namespace.
+// If the KV namespace is not bound, the counter is kept in the memory of the isolate instead.
+// KV has no transactions, so concurrent updates may overwrite each other.
+type kvStore struct {
+	binding string
+	key     string
+
+	fallback     counter.MemoryStore
+	fallbackOnce sync.Once
+}
+
+func (s *kvStore) namespace(ctx context.Context) *cloudflare.KVNamespace {
+	ns, err := cloudflare.NewKVNamespace(ctx, s.binding)
+	if err != nil {
+		s.fallbackOnce.Do(func() {
+			log.Printf("%s is not bound, keeping the counter in memory: %v", s.binding, err)
+		})
+		return nil
+	}
+	return ns
+}
+
+func (s *kvStore) Get(ctx context.Context) (counter.Counter, error) {
+	ns := s.namespace(ctx)
+	if ns == nil {
+		return s.fallback.Get(ctx)
+	}
+	v, err := ns.GetString(s.key, nil)
+	if err != nil {
+		return counter.Counter{}, err
+	}
+	var c counter.Counter
+	// A missing key is returned as null.
+	if v == "<null>" {
+		return c, nil
+	}
+	if err := json.Unmarshal([]byte(v), &c); err != nil {
+		return counter.Counter{}, err
+	}
+	return c, nil
+}
+
+func (s *kvStore) Put(ctx context.Context, c counter.Counter) error {
+	ns := s.namespace(ctx)
+	if ns == nil {
+		return s.fallback.Put(ctx, c)
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ns.PutString(s.key, string(b), nil)
+}
@@ -1,20 +1,167 @@
 package main
 
 import (
-	"html/template"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
 	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
 
-	"github.com/syumai/workers"
+	"github.com/syumai/workers-playground/go-html-template/counter"
+	"github.com/syumai/workers-playground/go-html-template/session"
+	"github.com/syumai/workers-playground/go-html-template/view"
 )
 
-func main() {
-	http.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
-		tmpl, _ := template.New("foo").Parse(`<div>{{.CounterValue}}</div>`)
+//go:embed templates static
+var files embed.FS
 
-		data := map[string]int{
-			"CounterValue": 10,
+// maxNameLength is the maximum length of the name in the counter form in characters.
+const maxNameLength = 40
+
+// newHandler returns the handler of the app with templates and static files read from fsys.
+func newHandler(fsys fs.FS, dev bool, store counter.Store) (http.Handler, error) {
+	v, err := view.New(fsys, view.Options{Dev: dev})
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/static/", v.StaticHandler())
+	mux.Handle("/test", &counterHandler{view: v, store: store})
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		v.Stream(w, r, "stream", nil,
+			view.Section{Name: "intro", Data: func(context.Context) (any, error) { return nil, nil }},
+			view.Section{Name: "fast", Data: delayed(start, 300*time.Millisecond, nil)},
+			view.Section{Name: "slow", Data: delayed(start, time.Second, []string{"alpha", "beta", "gamma"})},
+		)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			v.NotFound(w, r)
+			return
 		}
-		_ = tmpl.ExecuteTemplate(w, "foo", data)
+		v.Render(w, r, http.StatusOK, "index", nil)
+	})
+	return mux, nil
+}
+
+// delayed returns section data resolved after d, standing in for a slow backend.
+func delayed(start time.Time, d time.Duration, items []string) func(context.Context) (any, error) {
+	return func(ctx context.Context) (any, error) {
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return map[string]any{
+			"Elapsed": time.Since(start).Round(time.Millisecond).String(),
+			"Items":   items,
+		}, nil
+	}
+}
+
+// counterPage is the data of the counter page.
+type counterPage struct {
+	Counter   counter.Counter
+	CSRFToken string
+	Flash     *session.Flash
+}
+
+// counterHandler shows the counter and updates it by form POSTs.
+// A POST always redirects back to the page with a flash message, so reloading does not resubmit the form.
+type counterHandler struct {
+	view  *view.Renderer
+	store counter.Store
+}
+
+func (h *counterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.show(w, r)
+	case http.MethodPost:
+		h.update(w, r)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		h.view.Error(w, r, http.StatusMethodNotAllowed, nil)
+	}
+}
+
+func (h *counterHandler) show(w http.ResponseWriter, r *http.Request) {
+	c, err := h.store.Get(r.Context())
+	if err != nil {
+		h.view.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	token, err := session.CSRFToken(w, r)
+	if err != nil {
+		h.view.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	h.view.Render(w, r, http.StatusOK, "counter", counterPage{
+		Counter:   c,
+		CSRFToken: token,
+		Flash:     session.PopFlash(w, r),
 	})
-	workers.Serve(nil) // use http.DefaultServeMux
+}
+
+func (h *counterHandler) update(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 4<<10)
+	if err := r.ParseForm(); err != nil {
+		h.view.Error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err := session.VerifyCSRF(r); err != nil {
+		h.view.Error(w, r, http.StatusForbidden, err)
+		return
+	}
+	flash, err := h.apply(r)
+	if err != nil {
+		h.view.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if err := session.SetFlash(w, r, flash); err != nil {
+		h.view.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+}
+
+// apply applies the action of the form to the counter and returns the message to show.
+// Invalid input results in an error message, not an error.
+func (h *counterHandler) apply(r *http.Request) (session.Flash, error) {
+	name := strings.TrimSpace(r.PostForm.Get("name"))
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return session.Flash{Kind: "error", Message: fmt.Sprintf("The name must be at most %d characters.", maxNameLength)}, nil
+	}
+	if name == "" {
+		name = "Someone"
+	}
+	c, err := h.store.Get(r.Context())
+	if err != nil {
+		return session.Flash{}, err
+	}
+	var verb string
+	switch action := r.PostForm.Get("action"); action {
+	case "increment":
+		c.Value++
+		verb = "incremented"
+	case "decrement":
+		c.Value--
+		verb = "decremented"
+	case "reset":
+		c.Value = 0
+		verb = "reset"
+	default:
+		return session.Flash{Kind: "error", Message: fmt.Sprintf("Unknown action: %q", action)}, nil
+	}
+	c.UpdatedBy = name
+	c.UpdatedAt = time.Now().UTC()
+	if err := h.store.Put(r.Context(), c); err != nil {
+		return session.Flash{}, err
+	}
+	return session.Flash{Kind: "success", Message: fmt.Sprintf("%s %s the counter to %d.", name, verb, c.Value)}, nil
 }
@@ -0,0 +1,15 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"github.com/syumai/workers"
+)
+
+func main() {
+	h, err := newHandler(files, false, &kvStore{binding: "COUNTER", key: "counter"})
+	if err != nil {
+		panic(err)
+	}
+	workers.Serve(h)
+}
@@ -0,0 +1,33 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"flag"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/syumai/workers-playground/go-html-template/counter"
+)
+
+// main runs the app as a native HTTP server for development.
+// With -dev, templates and static files are read from disk on every request,
+// so edits are reflected without restarting. The counter is kept in memory.
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	dev := flag.Bool("dev", false, "reload templates and static files from disk")
+	flag.Parse()
+
+	var fsys fs.FS = files
+	if *dev {
+		fsys = os.DirFS(".")
+	}
+	h, err := newHandler(fsys, *dev, &counter.MemoryStore{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("listening on http://%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, h))
+}
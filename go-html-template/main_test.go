@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/syumai/workers-playground/go-html-template/counter"
+)
+
+// client keeps cookies between requests to the handler.
+type client struct {
+	t       *testing.T
+	h       http.Handler
+	cookies map[string]*http.Cookie
+}
+
+func (c *client) do(req *http.Request) *httptest.ResponseRecorder {
+	c.t.Helper()
+	for _, ck := range c.cookies {
+		req.AddCookie(ck)
+	}
+	rec := httptest.NewRecorder()
+	c.h.ServeHTTP(rec, req)
+	for _, ck := range rec.Result().Cookies() {
+		if ck.MaxAge < 0 {
+			delete(c.cookies, ck.Name)
+		} else {
+			c.cookies[ck.Name] = ck
+		}
+	}
+	return rec
+}
+
+func (c *client) post(form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(req)
+}
+
+func TestCounter(t *testing.T) {
+	store := &counter.MemoryStore{}
+	h, err := newHandler(files, false, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &client{t: t, h: h, cookies: map[string]*http.Cookie{}}
+
+	rec := c.do(httptest.NewRequest(http.MethodGet, "/test", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	token := c.cookies["csrf"].Value
+	if !strings.Contains(rec.Body.String(), `name="csrf_token" value="`+token+`"`) {
+		t.Fatal("form does not contain the CSRF token")
+	}
+
+	if rec := c.post(url.Values{"action": {"increment"}}); rec.Code != http.StatusForbidden {
+		t.Errorf("without token: status = %d", rec.Code)
+	}
+	if rec := c.post(url.Values{"action": {"increment"}, "csrf_token": {"forged"}}); rec.Code != http.StatusForbidden {
+		t.Errorf("forged token: status = %d", rec.Code)
+	}
+
+	const name = `<script>alert("x")</script>`
+	rec = c.post(url.Values{"action": {"increment"}, "name": {name}, "csrf_token": {token}})
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/test" {
+		t.Fatalf("status = %d, Location = %q; want redirect to /test", rec.Code, rec.Header().Get("Location"))
+	}
+	if got, _ := store.Get(context.Background()); got.Value != 1 || got.UpdatedBy != name {
+		t.Errorf("stored %+v", got)
+	}
+
+	rec = c.do(httptest.NewRequest(http.MethodGet, "/test", nil))
+	body := rec.Body.String()
+	if strings.Contains(body, "<script>") {
+		t.Error("user input is not escaped")
+	}
+	escaped := `&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;`
+	if want := `role="status">` + escaped + ` incremented the counter to 1.</p>`; !strings.Contains(body, want) {
+		t.Errorf("flash message %q not found in\n%s", want, body)
+	}
+	if want := `<strong>` + escaped + `</strong>`; !strings.Contains(body, want) {
+		t.Errorf("last updater %q not found", want)
+	}
+	if _, ok := c.cookies["flash"]; ok {
+		t.Error("flash was not cleared")
+	}
+	if body := c.do(httptest.NewRequest(http.MethodGet, "/test", nil)).Body.String(); strings.Contains(body, `role="status"`) {
+		t.Error("flash is shown twice")
+	}
+
+	c.post(url.Values{"action": {"explode"}, "csrf_token": {token}})
+	if body := c.do(httptest.NewRequest(http.MethodGet, "/test", nil)).Body.String(); !strings.Contains(body, `flash-error`) {
+		t.Error("invalid action did not show an error")
+	}
+	if got, _ := store.Get(context.Background()); got.Value != 1 {
+		t.Errorf("invalid action changed the counter to %d", got.Value)
+	}
+
+	if rec := c.do(httptest.NewRequest(http.MethodDelete, "/test", nil)); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: status = %d", rec.Code)
+	}
+}
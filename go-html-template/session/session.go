@@ -0,0 +1,114 @@
+// Package session provides CSRF tokens and flash messages kept in cookies.
+package session
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+const (
+	csrfCookie = "csrf"
+	// CSRFField is the name of the form field holding the CSRF token.
+	CSRFField   = "csrf_token"
+	flashCookie = "flash"
+)
+
+// ErrInvalidCSRFToken is returned by VerifyCSRF when the form token does not match the cookie.
+var ErrInvalidCSRFToken = errors.New("session: invalid CSRF token")
+
+func secure(req *http.Request) bool {
+	return req.TLS != nil || req.URL.Scheme == "https"
+}
+
+// CSRFToken returns the CSRF token of the client, issuing a new one in a cookie if there is none.
+// The token must be sent back in the CSRFField form field.
+//
+// This is the double-submit cookie pattern: another site can make the browser
+// send the cookie, but cannot read it to put it into the form. The SameSite
+// attribute of the cookie is a second line of defense.
+func CSRFToken(w http.ResponseWriter, req *http.Request) (string, error) {
+	if c, err := req.Cookie(csrfCookie); err == nil && c.Value != "" {
+		return c.Value, nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   secure(req),
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token, nil
+}
+
+// VerifyCSRF checks the CSRF token of a form POST. The form must be parsed beforehand.
+func VerifyCSRF(req *http.Request) error {
+	c, err := req.Cookie(csrfCookie)
+	if err != nil || c.Value == "" {
+		return ErrInvalidCSRFToken
+	}
+	token := req.PostForm.Get(CSRFField)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.Value)) != 1 {
+		return ErrInvalidCSRFToken
+	}
+	return nil
+}
+
+// Flash is a message shown once on the next page.
+type Flash struct {
+	// Kind is "success" or "error".
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// SetFlash stores f to be shown on the next page, typically before a redirect.
+// The message is not signed, so it must be rendered as text, as html/template does.
+func SetFlash(w http.ResponseWriter, req *http.Request, f Flash) error {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(b),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   secure(req),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// PopFlash returns the flash message of req and clears it, or nil if there is none.
+func PopFlash(w http.ResponseWriter, req *http.Request) *Flash {
+	c, err := req.Cookie(flashCookie)
+	if err != nil {
+		return nil
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure(req),
+		SameSite: http.SameSiteLaxMode,
+	})
+	b, err := base64.RawURLEncoding.DecodeString(c.Value)
+	if err != nil {
+		return nil
+	}
+	var f Flash
+	if err := json.Unmarshal(b, &f); err != nil || f.Message == "" {
+		return nil
+	}
+	return &f
+}
@@ -0,0 +1,102 @@
+package view
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// dateLayouts are the named layouts of the date function.
+var dateLayouts = map[string]string{
+	"date":     time.DateOnly,
+	"datetime": time.DateTime,
+	"time":     time.TimeOnly,
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123,
+}
+
+// Funcs returns the default template functions.
+//   - date formats a time with a named layout (date, datetime, time, rfc3339, rfc1123) or a Go layout: {{.Now | date "date"}}
+//   - url builds a path with query parameters from key-value pairs: {{url "/search" "q" .Query}}
+//   - asset returns the URL of a static file with a content hash for cache busting: {{asset "style.css"}}
+func Funcs(asset func(name string) (string, error)) template.FuncMap {
+	return template.FuncMap{
+		"date":  formatDate,
+		"url":   buildURL,
+		"asset": asset,
+	}
+}
+
+func formatDate(layout string, t time.Time) string {
+	if l, ok := dateLayouts[layout]; ok {
+		layout = l
+	}
+	return t.Format(layout)
+}
+
+func buildURL(path string, pairs ...any) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("url: odd number of query arguments: %d", len(pairs))
+	}
+	u := url.URL{Path: path}
+	q := url.Values{}
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return "", fmt.Errorf("url: query key must be a string: %v", pairs[i])
+		}
+		q.Add(key, fmt.Sprint(pairs[i+1]))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// assets hashes the files in the static directory.
+type assets struct {
+	fsys fs.FS
+	dev  bool
+
+	mu     sync.Mutex
+	hashes map[string]string
+}
+
+func newAssets(fsys fs.FS, dev bool) *assets {
+	sub, err := fs.Sub(fsys, "static")
+	if err != nil {
+		// fs.Sub only fails for an invalid directory name.
+		panic(err)
+	}
+	return &assets{fsys: sub, dev: dev, hashes: map[string]string{}}
+}
+
+// hash returns the content hash of the static file name, or "" if it does not exist.
+// Hashes are cached unless in dev mode.
+func (a *assets) hash(name string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if h, ok := a.hashes[name]; ok && !a.dev {
+		return h
+	}
+	b, err := fs.ReadFile(a.fsys, name)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	h := hex.EncodeToString(sum[:4])
+	a.hashes[name] = h
+	return h
+}
+
+// url returns the URL of the static file name with its content hash.
+func (a *assets) url(name string) (string, error) {
+	h := a.hash(name)
+	if h == "" {
+		return "", fmt.Errorf("asset: %s not found", name)
+	}
+	return "/static/" + name + "?v=" + h, nil
+}
@@ -0,0 +1,110 @@
+package view
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// Section is a part of a streamed page. Its data is resolved while the
+// preceding parts are already sent to the client.
+type Section struct {
+	// Name is the name of the template rendering the section, defined by the page.
+	Name string
+	// Data resolves the data of the section, passed to the template as Page.Data.
+	Data func(ctx context.Context) (any, error)
+}
+
+// sectionResult is the resolved data of a section.
+type sectionResult struct {
+	data any
+	err  error
+}
+
+// Stream renders page in chunks and writes each of them as soon as it is rendered,
+// so a slow section does not delay the first byte of the response.
+//
+//  1. "stream-head" of the layouts, the document up to the start of <main>, with data
+//  2. each section in order, as soon as its data and all preceding sections are resolved
+//  3. "stream-tail" of the layouts, the rest of the document, with data
+//
+// The data of all sections are resolved concurrently after the head is sent.
+// Once the head is sent, the status can no longer change: a failed section is
+// replaced by "stream-error" of the layouts, and the remaining sections are skipped.
+func (r *Renderer) Stream(w http.ResponseWriter, req *http.Request, page string, data any, sections ...Section) {
+	t, err := r.lookup(page)
+	if err != nil {
+		r.Error(w, req, http.StatusInternalServerError, err)
+		return
+	}
+	p := Page{Path: req.URL.Path, Now: r.opts.Now(), Data: data}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "stream-head", p); err != nil {
+		r.Error(w, req, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// Proxies such as nginx buffer responses unless this is set.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	flush := func() {
+		buf.WriteTo(w)
+		// On Workers, the body is a pipe read by the ReadableStream of the response,
+		// so each write is already sent and Flush returns http.ErrNotSupported.
+		// net/http servers buffer writes until they are flushed.
+		_ = rc.Flush()
+	}
+	flush()
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	results := make([]chan sectionResult, len(sections))
+	for i, s := range sections {
+		results[i] = make(chan sectionResult, 1)
+		go func(s Section, ch chan<- sectionResult) {
+			data, err := s.Data(ctx)
+			ch <- sectionResult{data: data, err: err}
+		}(s, results[i])
+	}
+	for i, s := range sections {
+		res := <-results[i]
+		err := res.err
+		if err == nil {
+			p.Data = res.data
+			err = t.ExecuteTemplate(&buf, s.Name, p)
+		}
+		if err != nil {
+			log.Printf("view: %s %s: section %s: %v", req.Method, req.URL.Path, s.Name, err)
+			buf.Reset()
+			r.streamError(t, &buf, p, err)
+			flush()
+			break
+		}
+		flush()
+	}
+	cancel()
+
+	p.Data = data
+	if err := t.ExecuteTemplate(&buf, "stream-tail", p); err != nil {
+		log.Printf("view: %s %s: stream-tail: %v", req.Method, req.URL.Path, err)
+		buf.Reset()
+		return
+	}
+	flush()
+}
+
+// streamError renders "stream-error" in place of a failed section.
+func (r *Renderer) streamError(t *template.Template, buf *bytes.Buffer, p Page, err error) {
+	data := ErrorData{Status: http.StatusInternalServerError, StatusText: http.StatusText(http.StatusInternalServerError)}
+	if r.opts.Dev {
+		data.Detail = err.Error()
+	}
+	p.Data = data
+	if err := t.ExecuteTemplate(buf, "stream-error", p); err != nil {
+		buf.Reset()
+		buf.WriteString("<p>" + template.HTMLEscapeString(data.StatusText) + "</p>")
+	}
+}
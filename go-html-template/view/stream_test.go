@@ -0,0 +1,146 @@
+package view
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// chunkRecorder records the body written between flushes as chunks.
+type chunkRecorder struct {
+	*httptest.ResponseRecorder
+	chunks  []string
+	pending strings.Builder
+	flushed chan string
+}
+
+func newChunkRecorder() *chunkRecorder {
+	return &chunkRecorder{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan string, 16)}
+}
+
+func (c *chunkRecorder) Write(b []byte) (int, error) {
+	c.pending.Write(b)
+	return c.ResponseRecorder.Write(b)
+}
+
+func (c *chunkRecorder) Flush() {
+	if c.pending.Len() == 0 {
+		return
+	}
+	chunk := c.pending.String()
+	c.pending.Reset()
+	c.chunks = append(c.chunks, chunk)
+	c.flushed <- chunk
+	c.ResponseRecorder.Flush()
+}
+
+func streamFS() fstest.MapFS {
+	fsys := testFS()
+	fsys["templates/layouts/stream.html"] = &fstest.MapFile{Data: []byte(
+		`{{define "stream-head"}}<head><title>{{template "title" .}}</title></head><main>{{end}}` +
+			`{{define "stream-tail"}}</main><footer>{{.Data}}</footer>{{end}}` +
+			`{{define "stream-error"}}<p>{{.Data.Status}}{{if .Data.Detail}}: {{.Data.Detail}}{{end}}</p>{{end}}`)}
+	fsys["templates/pages/streamed.html"] = &fstest.MapFile{Data: []byte(
+		`{{define "title"}}Streamed{{end}}` +
+			`{{define "a"}}<section>{{.Data}}</section>{{end}}` +
+			`{{define "b"}}<section>{{.Data.Missing}}</section>{{end}}`)}
+	return fsys
+}
+
+func value(v any) func(context.Context) (any, error) {
+	return func(context.Context) (any, error) { return v, nil }
+}
+
+func TestStreamChunks(t *testing.T) {
+	r, err := New(streamFS(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := newChunkRecorder()
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Stream(rec, httptest.NewRequest(http.MethodGet, "/s", nil), "streamed", "foot",
+			Section{Name: "a", Data: func(context.Context) (any, error) {
+				<-release
+				return "<first>", nil
+			}},
+			Section{Name: "a", Data: value("second")},
+		)
+	}()
+
+	// The head is flushed before the data of the first section is resolved.
+	if got, want := <-rec.flushed, "<head><title>Streamed</title></head><main>"; got != want {
+		t.Errorf("head chunk = %q, want %q", got, want)
+	}
+	close(release)
+	<-done
+
+	want := []string{
+		"<head><title>Streamed</title></head><main>",
+		"<section>&lt;first&gt;</section>",
+		"<section>second</section>",
+		"</main><footer>foot</footer>",
+	}
+	if strings.Join(rec.chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("chunks =\n%q\nwant\n%q", rec.chunks, want)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestStreamSectionError(t *testing.T) {
+	r, err := New(streamFS(), Options{Dev: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := newChunkRecorder()
+	r.Stream(rec, httptest.NewRequest(http.MethodGet, "/s", nil), "streamed", "foot",
+		Section{Name: "a", Data: value("ok")},
+		Section{Name: "a", Data: func(context.Context) (any, error) { return nil, errors.New("backend down") }},
+		Section{Name: "a", Data: value("skipped")},
+	)
+	want := []string{
+		"<head><title>Streamed</title></head><main>",
+		"<section>ok</section>",
+		"<p>500: backend down</p>",
+		"</main><footer>foot</footer>",
+	}
+	if strings.Join(rec.chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("chunks =\n%q\nwant\n%q", rec.chunks, want)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d; the status is sent with the head", rec.Code)
+	}
+
+	// A template error in a section is handled the same way.
+	rec = newChunkRecorder()
+	r.Stream(rec, httptest.NewRequest(http.MethodGet, "/s", nil), "streamed", "foot", Section{Name: "b", Data: value(1)})
+	if len(rec.chunks) != 3 || !strings.HasPrefix(rec.chunks[1], "<p>500: ") {
+		t.Errorf("chunks = %q", rec.chunks)
+	}
+}
+
+func TestStreamHeadError(t *testing.T) {
+	r, err := New(streamFS(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := newChunkRecorder()
+	r.Stream(rec, httptest.NewRequest(http.MethodGet, "/s", nil), "missing", nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500 before anything is sent", rec.Code)
+	}
+	if len(rec.chunks) != 0 {
+		t.Errorf("chunks = %q", rec.chunks)
+	}
+}
@@ -0,0 +1,200 @@
+// Package view renders html/template pages with a shared layout and partials.
+//
+// Templates are read from a file system with this layout:
+//
+//	templates/layouts/*.html  - define "layout", the document around every page
+//	templates/partials/*.html - templates shared by all pages
+//	templates/pages/*.html    - one page each, defining "title" and "content"
+//
+// A page is rendered by its file name without the extension, such as "index".
+package view
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Options are the options of a Renderer.
+type Options struct {
+	// Dev re-parses templates and re-hashes assets on every render, and shows
+	// error details on error pages. Use it with os.DirFS to edit templates
+	// without restarting.
+	Dev bool
+	// Funcs are added to the default functions. See Funcs.
+	Funcs template.FuncMap
+	// Now returns the current time. Defaults to time.Now in UTC.
+	Now func() time.Time
+}
+
+// Page is the value passed to templates as dot.
+type Page struct {
+	// Path is the request path, such as for highlighting the current navigation item.
+	Path string
+	// Now is the time of rendering.
+	Now time.Time
+	// Data is the page-specific data.
+	Data any
+}
+
+// ErrorData is the Data of the "error" page.
+type ErrorData struct {
+	Status     int
+	StatusText string
+	// Detail is the error message. It is only set in dev mode.
+	Detail string
+}
+
+// Renderer renders pages parsed from a file system.
+type Renderer struct {
+	fsys   fs.FS
+	opts   Options
+	assets *assets
+	pages  map[string]*template.Template
+}
+
+// New parses all templates of fsys.
+// Without Options.Dev, templates are parsed only here, once.
+func New(fsys fs.FS, opts Options) (*Renderer, error) {
+	if opts.Now == nil {
+		opts.Now = func() time.Time { return time.Now().UTC() }
+	}
+	r := &Renderer{
+		fsys:   fsys,
+		opts:   opts,
+		assets: newAssets(fsys, opts.Dev),
+	}
+	pages, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	r.pages = pages
+	return r, nil
+}
+
+// parse parses every page together with the layouts and partials.
+func (r *Renderer) parse() (map[string]*template.Template, error) {
+	base := template.New("").Funcs(Funcs(r.assets.url))
+	if r.opts.Funcs != nil {
+		base = base.Funcs(r.opts.Funcs)
+	}
+	for _, pattern := range []string{"templates/layouts/*.html", "templates/partials/*.html"} {
+		names, err := fs.Glob(r.fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			continue
+		}
+		if base, err = base.ParseFS(r.fsys, pattern); err != nil {
+			return nil, err
+		}
+	}
+	if base.Lookup("layout") == nil {
+		return nil, fmt.Errorf("view: no layout defined in templates/layouts")
+	}
+	names, err := fs.Glob(r.fsys, "templates/pages/*.html")
+	if err != nil {
+		return nil, err
+	}
+	pages := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		t, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if t, err = t.ParseFS(r.fsys, name); err != nil {
+			return nil, err
+		}
+		pages[strings.TrimSuffix(path.Base(name), ".html")] = t
+	}
+	return pages, nil
+}
+
+// lookup returns the template of page.
+func (r *Renderer) lookup(page string) (*template.Template, error) {
+	pages := r.pages
+	if r.opts.Dev {
+		var err error
+		if pages, err = r.parse(); err != nil {
+			return nil, err
+		}
+	}
+	t, ok := pages[page]
+	if !ok {
+		return nil, fmt.Errorf("view: page %q not found", page)
+	}
+	return t, nil
+}
+
+// Render renders page with data as the response with status.
+// The page is rendered into a buffer first, so a failed render results in
+// an error page instead of a partial response.
+func (r *Renderer) Render(w http.ResponseWriter, req *http.Request, status int, page string, data any) {
+	var buf bytes.Buffer
+	if err := r.execute(&buf, req, page, data); err != nil {
+		r.Error(w, req, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+func (r *Renderer) execute(buf *bytes.Buffer, req *http.Request, page string, data any) error {
+	t, err := r.lookup(page)
+	if err != nil {
+		return err
+	}
+	return t.ExecuteTemplate(buf, "layout", Page{
+		Path: req.URL.Path,
+		Now:  r.opts.Now(),
+		Data: data,
+	})
+}
+
+// Error renders the "error" page with status.
+// Server errors are logged. If the error page itself fails, a plain text response is sent.
+func (r *Renderer) Error(w http.ResponseWriter, req *http.Request, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Printf("view: %s %s: %v", req.Method, req.URL.Path, err)
+	}
+	data := ErrorData{Status: status, StatusText: http.StatusText(status)}
+	if r.opts.Dev && err != nil {
+		data.Detail = err.Error()
+	}
+	var buf bytes.Buffer
+	if err := r.execute(&buf, req, "error", data); err != nil {
+		log.Printf("view: render error page: %v", err)
+		http.Error(w, data.StatusText, status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// NotFound renders the "error" page with 404 Not Found.
+func (r *Renderer) NotFound(w http.ResponseWriter, req *http.Request) {
+	r.Error(w, req, http.StatusNotFound, nil)
+}
+
+// StaticHandler serves the files of the static directory, as linked by the asset function.
+// It must be mounted at /static/.
+// Requests with the current hash of the file are cached for a year.
+func (r *Renderer) StaticHandler() http.Handler {
+	files := http.StripPrefix("/static/", http.FileServer(http.FS(r.assets.fsys)))
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name := strings.TrimPrefix(req.URL.Path, "/static/")
+		if v := req.URL.Query().Get("v"); v != "" && v == r.assets.hash(name) {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		}
+		files.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,156 @@
+package view
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"templates/layouts/base.html": {Data: []byte(`{{define "layout"}}<title>{{template "title" .}}</title>{{template "nav" .}}<main>{{template "content" .}}</main>{{end}}`)},
+		"templates/partials/nav.html": {Data: []byte(`{{define "nav"}}<nav>{{.Path}}</nav>{{end}}`)},
+		"templates/pages/hello.html":  {Data: []byte(`{{define "title"}}Hello{{end}}{{define "content"}}<p>{{.Data}}</p><a href="{{url "/search" "q" .Data}}">s</a>{{end}}`)},
+		"templates/pages/funcs.html":  {Data: []byte(`{{define "title"}}Funcs{{end}}{{define "content"}}{{.Now | date "date"}}|{{.Now | date "15:04"}}|{{asset "app.css"}}{{end}}`)},
+		"templates/pages/broken.html": {Data: []byte(`{{define "title"}}Broken{{end}}{{define "content"}}{{.Data.Missing}}{{end}}`)},
+		"templates/pages/error.html":  {Data: []byte(`{{define "title"}}Error{{end}}{{define "content"}}{{.Data.Status}} {{.Data.StatusText}}{{if .Data.Detail}} ({{.Data.Detail}}){{end}}{{end}}`)},
+		"static/app.css":              {Data: []byte(`body{}`)},
+	}
+}
+
+func render(t *testing.T, r *Renderer, page string, data any) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.Render(rec, httptest.NewRequest(http.MethodGet, "/page", nil), http.StatusOK, page, data)
+	return rec
+}
+
+func TestRender(t *testing.T) {
+	r, err := New(testFS(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := render(t, r, "hello", "<b>a&b</b>")
+	want := `<title>Hello</title><nav>/page</nav><main><p>&lt;b&gt;a&amp;b&lt;/b&gt;</p><a href="/search?q=%3Cb%3Ea%26b%3C%2Fb%3E">s</a></main>`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	r, err := New(testFS(), Options{Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := render(t, r, "funcs", nil).Body.String()
+	hash := r.assets.hash("app.css")
+	if want := "2024-05-06|07:08|/static/app.css?v=" + hash; !strings.Contains(body, want) {
+		t.Errorf("body %q does not contain %q", body, want)
+	}
+	if len(hash) != 8 {
+		t.Errorf("hash = %q", hash)
+	}
+	if _, err := buildURL("/x", "odd"); err == nil {
+		t.Error("url with odd arguments: want error")
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	r, err := New(testFS(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := render(t, r, "broken", 1)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d", rec.Code)
+	}
+	if got, want := rec.Body.String(), "<main>500 Internal Server Error</main>"; !strings.Contains(got, want) {
+		t.Errorf("body %q does not contain %q", got, want)
+	}
+	if strings.Contains(rec.Body.String(), "Missing") {
+		t.Error("error details are shown outside dev mode")
+	}
+
+	rec = render(t, r, "nonexistent", nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("missing page: status = %d", rec.Code)
+	}
+
+	dev, err := New(testFS(), Options{Dev: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	dev.Error(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusBadGateway, errors.New("upstream down"))
+	if got := rec.Body.String(); rec.Code != http.StatusBadGateway || !strings.Contains(got, "(upstream down)") {
+		t.Errorf("dev error page: %d %q", rec.Code, got)
+	}
+}
+
+func TestDevReload(t *testing.T) {
+	fsys := testFS()
+	r, err := New(fsys, Options{Dev: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := r.assets.hash("app.css")
+	fsys["templates/pages/hello.html"] = &fstest.MapFile{Data: []byte(`{{define "title"}}Hi{{end}}{{define "content"}}reloaded{{end}}`)}
+	fsys["static/app.css"] = &fstest.MapFile{Data: []byte(`body{color:red}`)}
+	if got := render(t, r, "hello", nil).Body.String(); !strings.Contains(got, "reloaded") {
+		t.Errorf("template was not reloaded: %q", got)
+	}
+	if r.assets.hash("app.css") == before {
+		t.Error("asset hash was not updated")
+	}
+
+	prod, err := New(fsys, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys["templates/pages/hello.html"] = &fstest.MapFile{Data: []byte(`{{define "title"}}Hi{{end}}{{define "content"}}changed{{end}}`)}
+	if got := render(t, prod, "hello", nil).Body.String(); !strings.Contains(got, "reloaded") {
+		t.Errorf("templates must be parsed once outside dev mode: %q", got)
+	}
+}
+
+func TestNewError(t *testing.T) {
+	fsys := testFS()
+	fsys["templates/pages/bad.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}{{end`)}
+	if _, err := New(fsys, Options{}); err == nil {
+		t.Error("syntax error: want error")
+	}
+	delete(fsys, "templates/layouts/base.html")
+	delete(fsys, "templates/pages/bad.html")
+	if _, err := New(fsys, Options{}); err == nil {
+		t.Error("no layout: want error")
+	}
+}
+
+func TestStaticHandler(t *testing.T) {
+	r, err := New(testFS(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := r.StaticHandler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/app.css?v="+r.assets.hash("app.css"), nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "body{}" {
+		t.Fatalf("%d %q", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Cache-Control"); !strings.Contains(got, "immutable") {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/app.css?v=stale", nil))
+	if got := rec.Header().Get("Cache-Control"); got != "" {
+		t.Errorf("stale hash: Cache-Control = %q", got)
+	}
+}
@@ -0,0 +1,130 @@
+package avatar
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/syumai/syumaigen"
+)
+
+const (
+	DefaultFrames = 12
+	MinFrames     = 2
+	MaxFrames     = 36
+
+	// DefaultDelay is the delay between frames in 100ths of a second.
+	DefaultDelay = 8
+	MinDelay     = 2
+	MaxDelay     = 100
+)
+
+// AnimationOptions are the options of an animated avatar.
+type AnimationOptions struct {
+	Options
+	// Frames is the number of frames in one full hue rotation.
+	Frames int
+	// Delay is the delay between frames in 100ths of a second.
+	Delay int
+}
+
+// ParseAnimationOptions parses the frames and delay query parameters in addition to ParseOptions.
+func ParseAnimationOptions(q url.Values) (AnimationOptions, error) {
+	opts, err := ParseOptions(q)
+	if err != nil {
+		return AnimationOptions{}, err
+	}
+	frames, err := intParam(q, "frames", DefaultFrames, MinFrames, MaxFrames)
+	if err != nil {
+		return AnimationOptions{}, err
+	}
+	delay, err := intParam(q, "delay", DefaultDelay, MinDelay, MaxDelay)
+	if err != nil {
+		return AnimationOptions{}, err
+	}
+	return AnimationOptions{Options: opts, Frames: frames, Delay: delay}, nil
+}
+
+// intParam parses the query parameter name as an integer from min to max.
+func intParam(q url.Values, name string, def, min, max int) (int, error) {
+	s := q.Get(name)
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("%s must be an integer from %d to %d: %q", name, min, max, s)
+	}
+	return v, nil
+}
+
+// HueRotate returns a copy of cMap with the hue of every color rotated by degrees in the HCL space.
+// Lightness, chroma and alpha are kept.
+func HueRotate(cMap syumaigen.ColorMap, degrees float64) syumaigen.ColorMap {
+	out := make(syumaigen.ColorMap, len(cMap))
+	for k, c := range cMap {
+		nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+		if nc.A == 0 {
+			out[k] = c
+			continue
+		}
+		cf := colorful.Color{R: float64(nc.R) / 0xff, G: float64(nc.G) / 0xff, B: float64(nc.B) / 0xff}
+		h, ch, l := cf.Hcl()
+		r, g, b := colorful.Hcl(math.Mod(h+degrees, 360), ch, l).Clamped().RGB255()
+		out[k] = color.NRGBA{R: r, G: g, B: b, A: nc.A}
+	}
+	return out
+}
+
+// Animate renders an animated GIF cycling through a full hue rotation of cMap.
+func Animate(pattern [][]int, cMap syumaigen.ColorMap, scale, frames, delay int) (*gif.GIF, error) {
+	if frames < MinFrames || frames > MaxFrames {
+		return nil, fmt.Errorf("avatar: frames out of range: %d", frames)
+	}
+	g := &gif.GIF{
+		Image: make([]*image.Paletted, frames),
+		Delay: make([]int, frames),
+	}
+	for i := range frames {
+		img, err := Render(pattern, HueRotate(cMap, 360*float64(i)/float64(frames)), scale)
+		if err != nil {
+			return nil, err
+		}
+		g.Image[i] = img
+		g.Delay[i] = delay
+	}
+	return g, nil
+}
+
+// Animated serves an animated GIF avatar cycling through a hue rotation of its colors.
+// It takes the query parameters of ParseAnimationOptions.
+func (h *Handler) Animated(w http.ResponseWriter, req *http.Request) {
+	opts, err := ParseAnimationOptions(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	colorCode := opts.ColorCode()
+	var key string
+	if opts.Deterministic() {
+		key = cacheKey("animated", colorCode, opts.Pattern, opts.Scale, opts.Frames, opts.Delay)
+	}
+	h.serve(w, req, key, GIF.ContentType(), func() (encodeFunc, error) {
+		pattern, err := SelectPattern(h.Pattern, opts.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		g, err := Animate(pattern, h.ColorMap(colorCode), opts.Scale, opts.Frames, opts.Delay)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer) error { return gif.EncodeAll(w, g) }, nil
+	})
+}
@@ -0,0 +1,80 @@
+package avatar
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestHueRotate(t *testing.T) {
+	full := HueRotate(testColorMap, 360)
+	half := HueRotate(testColorMap, 180)
+	for k, c := range testColorMap {
+		if !sameColor(full[k], c, 1) {
+			t.Errorf("360 degrees: %d = %v, want %v", k, full[k], c)
+		}
+	}
+	if sameColor(half[1], testColorMap[1], 8) {
+		t.Errorf("180 degrees did not change %v", testColorMap[1])
+	}
+	transparent := map[int]color.Color{0: color.Transparent}
+	if got := HueRotate(transparent, 90)[0]; got != color.Transparent {
+		t.Errorf("transparent color changed to %v", got)
+	}
+}
+
+func TestAnimated(t *testing.T) {
+	h := newTestHandler(&MemoryCache{})
+	rec := serve(http.HandlerFunc(h.Animated), "/generate/animated?seed=alice&scale=2&frames=6&delay=5", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "image/gif" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if rec.Header().Get("ETag") == "" {
+		t.Error("no ETag")
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 6 {
+		t.Fatalf("frames = %d, want 6", len(g.Image))
+	}
+	for i, d := range g.Delay {
+		if d != 5 {
+			t.Errorf("delay[%d] = %d, want 5", i, d)
+		}
+	}
+	// The first frame is the unrotated avatar.
+	if got, want := g.Image[0].At(2, 0), testColorMap[1]; !sameColor(got, want, 1) {
+		t.Errorf("first frame = %v, want %v", got, want)
+	}
+	if sameColor(g.Image[3].At(2, 0), testColorMap[1], 8) {
+		t.Error("hue is not rotated in the middle of the animation")
+	}
+
+	serve(http.HandlerFunc(h.Animated), "/generate/animated?seed=alice&scale=2&frames=6&delay=5", nil)
+	if h.generated != 1 {
+		t.Errorf("generated %d times, want 1", h.generated)
+	}
+	for _, q := range []string{"frames=1", "frames=37", "delay=1", "scale=0"} {
+		if rec := serve(http.HandlerFunc(h.Animated), "/generate/animated?"+q, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", q, rec.Code)
+		}
+	}
+}
+
+func TestParseAnimationOptions(t *testing.T) {
+	opts, err := ParseAnimationOptions(url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Frames != DefaultFrames || opts.Delay != DefaultDelay || opts.Scale != DefaultScale {
+		t.Errorf("defaults = %+v", opts)
+	}
+}
@@ -0,0 +1,255 @@
+// Package avatar renders syumaigen avatar images from request options.
+package avatar
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math/rand/v2"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/syumai/syumaigen"
+)
+
+const (
+	DefaultScale = 10
+	MinScale     = 1
+	MaxScale     = 32
+)
+
+// Options are the options of a generated avatar.
+type Options struct {
+	// Seed is hashed to the avatar color, such as a username or an email address.
+	Seed string
+	// Color is the avatar color code like "e4572e". It takes precedence over Seed.
+	// If both are empty, a random color is used.
+	Color string
+	// Scale is the size of a pattern cell in pixels.
+	Scale int
+	// Pattern is the name of the pattern variant. See Patterns.
+	Pattern string
+}
+
+var colorCodeRe = regexp.MustCompile(`^[0-9a-f]{6}$`)
+
+// ParseOptions parses the seed, color, scale and pattern query parameters.
+func ParseOptions(q url.Values) (Options, error) {
+	opts := Options{
+		Seed:    q.Get("seed"),
+		Color:   strings.ToLower(strings.TrimPrefix(q.Get("color"), "#")),
+		Scale:   DefaultScale,
+		Pattern: q.Get("pattern"),
+	}
+	if opts.Color != "" && !colorCodeRe.MatchString(opts.Color) {
+		return Options{}, fmt.Errorf("color must be a 6-digit hex color code: %q", q.Get("color"))
+	}
+	if s := q.Get("scale"); s != "" {
+		scale, err := strconv.Atoi(s)
+		if err != nil || scale < MinScale || scale > MaxScale {
+			return Options{}, fmt.Errorf("scale must be an integer from %d to %d: %q", MinScale, MaxScale, s)
+		}
+		opts.Scale = scale
+	}
+	if opts.Pattern == "" {
+		opts.Pattern = "default"
+	}
+	if _, ok := Patterns[opts.Pattern]; !ok {
+		return Options{}, fmt.Errorf("unknown pattern: %q", opts.Pattern)
+	}
+	return opts, nil
+}
+
+// Deterministic reports whether the options always produce the same image.
+func (o Options) Deterministic() bool {
+	return o.Color != "" || o.Seed != ""
+}
+
+// ColorCode returns the color code of the avatar without "#".
+func (o Options) ColorCode() string {
+	switch {
+	case o.Color != "":
+		return o.Color
+	case o.Seed != "":
+		return SeedColorCode(o.Seed)
+	}
+	return RandomColorCode()
+}
+
+// RandomColorCode returns a random color code.
+func RandomColorCode() string {
+	return hclColorCode(rand.Float64(), rand.Float64())
+}
+
+// SeedColorCode returns the color code for seed. Seeds are compared
+// case-insensitively ignoring surrounding spaces, so an email address
+// maps to the same color however it is typed.
+func SeedColorCode(seed string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(seed))))
+	h := float64(binary.BigEndian.Uint32(sum[0:4])) / (1 << 32)
+	c := float64(binary.BigEndian.Uint32(sum[4:8])) / (1 << 32)
+	return hclColorCode(h, c)
+}
+
+// hclColorCode maps hue and chroma fractions in [0, 1) to an HCL color with a fixed luminance.
+func hclColorCode(hue, chroma float64) string {
+	h := hue * 360.0
+	c := 0.4 + chroma*0.6
+	l := 0.5
+	return strings.TrimLeft(colorful.Hcl(h, c, l).Hex(), "#")
+}
+
+// Patterns are the pattern variants selectable by name.
+// Each one transforms the base pattern of syumaigen.
+var Patterns = map[string]func(base [][]int) [][]int{
+	"default": func(base [][]int) [][]int { return base },
+	"mirror":  mirror,
+	"flip":    flip,
+	"rotate":  rotate,
+}
+
+// SelectPattern returns the pattern variant name of base.
+func SelectPattern(base [][]int, name string) ([][]int, error) {
+	transform, ok := Patterns[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown pattern: %q", name)
+	}
+	return transform(base), nil
+}
+
+// mirror flips the pattern horizontally.
+func mirror(base [][]int) [][]int {
+	out := make([][]int, len(base))
+	for y, row := range base {
+		out[y] = make([]int, len(row))
+		for x, v := range row {
+			out[y][len(row)-1-x] = v
+		}
+	}
+	return out
+}
+
+// flip flips the pattern vertically.
+func flip(base [][]int) [][]int {
+	out := make([][]int, len(base))
+	for y, row := range base {
+		out[len(base)-1-y] = append([]int(nil), row...)
+	}
+	return out
+}
+
+// rotate rotates the pattern by 90 degrees clockwise.
+func rotate(base [][]int) [][]int {
+	if len(base) == 0 {
+		return nil
+	}
+	h, w := len(base), len(base[0])
+	out := make([][]int, w)
+	for x := range out {
+		out[x] = make([]int, h)
+		for y := range h {
+			out[x][h-1-y] = base[y][x]
+		}
+	}
+	return out
+}
+
+// Image is a rendered avatar ready to be encoded in any Format.
+type Image struct {
+	pattern [][]int
+	cMap    syumaigen.ColorMap
+	scale   int
+	img     *image.Paletted
+}
+
+// New renders the avatar. All validation happens here, so once New succeeds,
+// encoding can only fail by a write error and the response can be streamed.
+func New(pattern [][]int, cMap syumaigen.ColorMap, scale int) (*Image, error) {
+	img, err := Render(pattern, cMap, scale)
+	if err != nil {
+		return nil, err
+	}
+	return &Image{pattern: pattern, cMap: cMap, scale: scale, img: img}, nil
+}
+
+// Render draws the avatar with syumaigen.GenerateImage and converts it to
+// a paletted image, so encoding it is compact and deterministic.
+func Render(pattern [][]int, cMap syumaigen.ColorMap, scale int) (*image.Paletted, error) {
+	// GenerateImage has no upper bound of scale, which would let a request allocate any size.
+	if scale < MinScale || scale > MaxScale {
+		return nil, fmt.Errorf("avatar: scale out of range: %d", scale)
+	}
+	img, err := syumaigen.GenerateImage(pattern, cMap, scale)
+	if err != nil {
+		return nil, fmt.Errorf("avatar: %w", err)
+	}
+	palette, err := patternPalette(pattern, cMap)
+	if err != nil {
+		return nil, err
+	}
+	p := image.NewPaletted(img.Bounds(), palette)
+	// Every pixel is exactly a palette color, so this only looks up indexes.
+	draw.Draw(p, p.Rect, img, image.Point{}, draw.Src)
+	return p, nil
+}
+
+// patternPalette returns the colors of pattern in the order of first appearance,
+// so that the palette does not depend on the iteration order of cMap.
+// The pattern must already be validated by syumaigen.GenerateImage.
+func patternPalette(pattern [][]int, cMap syumaigen.ColorMap) (color.Palette, error) {
+	var palette color.Palette
+	seen := map[int]bool{}
+	for _, row := range pattern {
+		for _, v := range row {
+			if seen[v] {
+				continue
+			}
+			if len(palette) == 256 {
+				return nil, errors.New("avatar: too many colors")
+			}
+			seen[v] = true
+			palette = append(palette, cMap[v])
+		}
+	}
+	return palette, nil
+}
+
+// pngBufferPool shares the compression buffers of pngEncoder between requests.
+type pngBufferPool struct {
+	pool sync.Pool
+}
+
+func (p *pngBufferPool) Get() *png.EncoderBuffer {
+	b, _ := p.pool.Get().(*png.EncoderBuffer)
+	return b
+}
+
+func (p *pngBufferPool) Put(b *png.EncoderBuffer) {
+	p.pool.Put(b)
+}
+
+// pngEncoder favors speed over size: avatars are a few flat colors, so
+// BestSpeed compresses them nearly as well as the default level.
+var pngEncoder = &png.Encoder{
+	CompressionLevel: png.BestSpeed,
+	BufferPool:       &pngBufferPool{},
+}
+
+// WritePNG renders the avatar and writes it to w as a PNG.
+func WritePNG(w io.Writer, pattern [][]int, cMap syumaigen.ColorMap, scale int) error {
+	img, err := Render(pattern, cMap, scale)
+	if err != nil {
+		return err
+	}
+	return pngEncoder.Encode(w, img)
+}
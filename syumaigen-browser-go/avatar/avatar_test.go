@@ -0,0 +1,159 @@
+package avatar
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image/color"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syumai/syumaigen"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+var testPattern = [][]int{
+	{0, 1, 1, 0},
+	{1, 2, 2, 1},
+	{0, 2, 0, 0},
+	{1, 0, 0, 3},
+}
+
+var testColorMap = syumaigen.ColorMap{
+	0: color.RGBA{0xff, 0xff, 0xff, 0xff},
+	1: color.RGBA{0xe4, 0x57, 0x2e, 0xff},
+	2: color.RGBA{0x29, 0x33, 0x5c, 0xff},
+	3: color.RGBA{0xf3, 0xa7, 0x12, 0xff},
+}
+
+func TestWritePNGGolden(t *testing.T) {
+	for name := range Patterns {
+		for _, scale := range []int{1, 3} {
+			t.Run(fmt.Sprintf("%s-x%d", name, scale), func(t *testing.T) {
+				pattern, err := SelectPattern(testPattern, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var buf bytes.Buffer
+				if err := WritePNG(&buf, pattern, testColorMap, scale); err != nil {
+					t.Fatal(err)
+				}
+				golden := filepath.Join("testdata", fmt.Sprintf("%s-x%d.png", name, scale))
+				if *update {
+					if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(buf.Bytes(), want) {
+					t.Errorf("output differs from %s; run go test -update to regenerate", golden)
+				}
+			})
+		}
+	}
+}
+
+func TestWritePNGDeterministic(t *testing.T) {
+	var a, b bytes.Buffer
+	if err := WritePNG(&a, testPattern, testColorMap, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := WritePNG(&b, testPattern, testColorMap, 10); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("identical inputs produced different bytes")
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	// The messages come from syumaigen.GenerateImage, wrapped with the package prefix.
+	tests := map[string]struct {
+		pattern [][]int
+		scale   int
+		want    string
+	}{
+		"empty pattern":   {pattern: nil, scale: 1, want: "avatar: data is blank"},
+		"ragged pattern":  {pattern: [][]int{{0, 1}, {0}}, scale: 1, want: "avatar: line length is not equal, want: 2, got: 1"},
+		"missing color":   {pattern: [][]int{{9}}, scale: 1, want: "avatar: color not found: 9"},
+		"scale too small": {pattern: testPattern, scale: 0, want: "avatar: scale out of range: 0"},
+		"scale too large": {pattern: testPattern, scale: MaxScale + 1, want: fmt.Sprintf("avatar: scale out of range: %d", MaxScale+1)},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Render(tt.pattern, testColorMap, tt.scale)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeedColorCode(t *testing.T) {
+	a := SeedColorCode("alice@example.com")
+	if b := SeedColorCode("  Alice@Example.com "); a != b {
+		t.Errorf("seed should be normalized: %s != %s", a, b)
+	}
+	if b := SeedColorCode("bob@example.com"); a == b {
+		t.Errorf("different seeds got the same color %s", a)
+	}
+	if !colorCodeRe.MatchString(a) {
+		t.Errorf("invalid color code %q", a)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    Options
+		wantErr bool
+	}{
+		{query: "", want: Options{Scale: DefaultScale, Pattern: "default"}},
+		{query: "seed=alice&scale=4&pattern=mirror", want: Options{Seed: "alice", Scale: 4, Pattern: "mirror"}},
+		{query: "color=%23E4572E", want: Options{Color: "e4572e", Scale: DefaultScale, Pattern: "default"}},
+		{query: "color=red", wantErr: true},
+		{query: "scale=0", wantErr: true},
+		{query: "scale=33", wantErr: true},
+		{query: "scale=abc", wantErr: true},
+		{query: "pattern=spiral", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseOptions(q)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("want error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsColorCode(t *testing.T) {
+	if got := (Options{Seed: "alice", Color: "112233"}).ColorCode(); got != "112233" {
+		t.Errorf("color should take precedence over seed: %s", got)
+	}
+	if got, want := (Options{Seed: "alice"}).ColorCode(), SeedColorCode("alice"); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if (Options{}).Deterministic() {
+		t.Error("options without seed or color should not be deterministic")
+	}
+}
@@ -0,0 +1,110 @@
+package avatar
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// cacheVersion is part of every cache key. Bump it whenever the encoded
+// bytes of an avatar change so that stale cache entries and ETags are not reused.
+const cacheVersion = "v1"
+
+// ErrCacheMiss is returned by Cache.Get when the key is not cached.
+var ErrCacheMiss = errors.New("avatar: cache miss")
+
+// Cache stores encoded avatars by key.
+type Cache interface {
+	// Get returns the cached image for key, or ErrCacheMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put stores the image for key with the given content type.
+	Put(ctx context.Context, key, contentType string, data []byte) error
+}
+
+// CacheKey returns the cache key of the avatar for the given color code, options and format.
+// The key only depends on inputs that change the output bytes, and is also used as the ETag.
+func CacheKey(colorCode string, opts Options, f Format) string {
+	return cacheKey(colorCode, opts.Pattern, opts.Scale, f)
+}
+
+// cacheKey hashes parts into a cache key.
+func cacheKey(parts ...any) string {
+	h := sha256.New()
+	fmt.Fprint(h, cacheVersion)
+	for _, p := range parts {
+		fmt.Fprintf(h, "\x00%v", p)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// TieredCache looks up caches in order and fills the earlier tiers on a hit in a later one.
+// Put stores into all tiers.
+type TieredCache []Cache
+
+func (c TieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	for i, tier := range c {
+		data, err := tier.Get(ctx, key)
+		if errors.Is(err, ErrCacheMiss) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, prev := range c[:i] {
+			// Backfilling is best effort; the data is already available.
+			_ = prev.Put(ctx, key, "", data)
+		}
+		return data, nil
+	}
+	return nil, ErrCacheMiss
+}
+
+func (c TieredCache) Put(ctx context.Context, key, contentType string, data []byte) error {
+	var errs []error
+	for _, tier := range c {
+		if err := tier.Put(ctx, key, contentType, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MemoryCache is a Cache in memory holding at most Size entries.
+// The oldest entry is evicted first.
+type MemoryCache struct {
+	Size int
+
+	mu      sync.Mutex
+	entries map[string][]byte
+	order   []string
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.entries[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return data, nil
+}
+
+func (c *MemoryCache) Put(_ context.Context, key, _ string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string][]byte{}
+	}
+	if _, ok := c.entries[key]; !ok {
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = data
+	for c.Size > 0 && len(c.order) > c.Size {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	return nil
+}
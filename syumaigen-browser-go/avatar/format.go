@@ -0,0 +1,169 @@
+package avatar
+
+import (
+	"fmt"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/syumai/syumaigen"
+)
+
+// Format is an output image format.
+type Format string
+
+const (
+	PNG  Format = "png"
+	GIF  Format = "gif"
+	JPEG Format = "jpeg"
+	SVG  Format = "svg"
+)
+
+// formats are the supported formats in the order of preference for wildcard Accept entries.
+var formats = []Format{PNG, SVG, GIF, JPEG}
+
+// ContentType returns the media type of f.
+func (f Format) ContentType() string {
+	switch f {
+	case SVG:
+		return "image/svg+xml"
+	default:
+		return "image/" + string(f)
+	}
+}
+
+// ParseFormat parses a format name such as "png" or "jpg".
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "png":
+		return PNG, nil
+	case "gif":
+		return GIF, nil
+	case "jpeg", "jpg":
+		return JPEG, nil
+	case "svg":
+		return SVG, nil
+	}
+	return "", fmt.Errorf("unknown format: %q", name)
+}
+
+// NegotiateFormat selects the output format.
+// A non-empty format parameter takes precedence over the Accept header.
+// Otherwise the supported media type with the highest quality in accept is used,
+// and PNG is returned when nothing in accept matches.
+func NegotiateFormat(format, accept string) (Format, error) {
+	if format != "" {
+		return ParseFormat(format)
+	}
+	best, bestQ := PNG, 0.0
+	for _, f := range formats {
+		if q := acceptQuality(accept, f.ContentType()); q > bestQ {
+			best, bestQ = f, q
+		}
+	}
+	return best, nil
+}
+
+// acceptQuality returns the quality of mediaType in accept.
+// The most specific matching entry is used, so "image/*;q=0.5, image/gif" gives image/gif a quality of 1.
+func acceptQuality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, entry := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(entry))
+		if err != nil {
+			continue
+		}
+		var s int
+		switch mt {
+		case mediaType:
+			s = 2
+		case typ + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+		specificity, q = s, 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+	}
+	return q
+}
+
+// Encode renders the avatar and writes it to w in format f.
+func Encode(w io.Writer, f Format, pattern [][]int, cMap syumaigen.ColorMap, scale int) error {
+	img, err := New(pattern, cMap, scale)
+	if err != nil {
+		return err
+	}
+	return img.Encode(w, f)
+}
+
+// Encode writes the avatar to w in format f.
+func (a *Image) Encode(w io.Writer, f Format) error {
+	switch f {
+	case PNG:
+		return pngEncoder.Encode(w, a.img)
+	case GIF:
+		return gif.Encode(w, a.img, nil)
+	case JPEG:
+		return jpeg.Encode(w, a.img, &jpeg.Options{Quality: 100})
+	case SVG:
+		return WriteSVG(w, a.pattern, a.cMap, a.scale)
+	}
+	return fmt.Errorf("avatar: unsupported format: %q", f)
+}
+
+// WriteSVG writes the avatar to w as an SVG image.
+// Each horizontal run of same-colored cells becomes one rect in a viewBox of
+// one unit per cell, so the image is small and stays sharp at any size.
+// The width and height attributes are the pattern size multiplied by scale.
+//
+// It draws the same cells as syumaigen.GenerateSVG, which writes one styled
+// square per cell in pixel coordinates. Merging runs makes the output about a
+// third of that size, and partially transparent colors keep their alpha as
+// fill-opacity instead of being drawn opaque.
+func WriteSVG(w io.Writer, pattern [][]int, cMap syumaigen.ColorMap, scale int) error {
+	// Render validates the arguments, so the SVG fails in the same cases as the raster formats.
+	if _, err := Render(pattern, cMap, 1); err != nil {
+		return err
+	}
+	if scale < MinScale || scale > MaxScale {
+		return fmt.Errorf("avatar: scale out of range: %d", scale)
+	}
+	h, wd := len(pattern), len(pattern[0])
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, wd*scale, h*scale, wd, h)
+	for y, row := range pattern {
+		for x := 0; x < len(row); {
+			end := x + 1
+			for end < len(row) && row[end] == row[x] {
+				end++
+			}
+			c := color.NRGBAModel.Convert(cMap[row[x]]).(color.NRGBA)
+			if c.A != 0 {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="1" fill="#%02x%02x%02x"`, x, y, end-x, c.R, c.G, c.B)
+				if c.A != 0xff {
+					fmt.Fprintf(&b, ` fill-opacity="%s"`, strconv.FormatFloat(float64(c.A)/0xff, 'f', 3, 64))
+				}
+				b.WriteString("/>")
+			}
+			x = end
+		}
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
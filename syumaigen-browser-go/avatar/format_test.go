@@ -0,0 +1,208 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/syumai/syumaigen"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		format, accept string
+		want           Format
+		wantErr        bool
+	}{
+		{want: PNG},
+		{accept: "text/html", want: PNG},
+		{accept: "*/*", want: PNG},
+		{accept: "image/svg+xml", want: SVG},
+		{accept: "image/gif, image/png;q=0.8", want: GIF},
+		{accept: "image/webp,image/*;q=0.8", want: PNG},
+		{accept: "image/*;q=0.5, image/jpeg", want: JPEG},
+		{accept: "image/png;q=0, image/gif;q=0.1", want: GIF},
+		{format: "jpg", accept: "image/png", want: JPEG},
+		{format: "SVG", want: SVG},
+		{format: "webp", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+"|"+tt.accept, func(t *testing.T) {
+			got, err := NegotiateFormat(tt.format, tt.accept)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("want error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// decodeSVG rasterizes the rects written by WriteSVG at one pixel per cell.
+func decodeSVG(t *testing.T, data []byte, scale int) image.Image {
+	t.Helper()
+	var doc struct {
+		Width   int    `xml:"width,attr"`
+		Height  int    `xml:"height,attr"`
+		ViewBox string `xml:"viewBox,attr"`
+		Rects   []struct {
+			X      int    `xml:"x,attr"`
+			Y      int    `xml:"y,attr"`
+			Width  int    `xml:"width,attr"`
+			Height int    `xml:"height,attr"`
+			Fill   string `xml:"fill,attr"`
+		} `xml:"rect"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	var w, h int
+	if _, err := fmt.Sscanf(doc.ViewBox, "0 0 %d %d", &w, &h); err != nil {
+		t.Fatalf("invalid viewBox %q: %v", doc.ViewBox, err)
+	}
+	if doc.Width != w*scale || doc.Height != h*scale {
+		t.Errorf("size = %dx%d, want %dx%d", doc.Width, doc.Height, w*scale, h*scale)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for _, r := range doc.Rects {
+		var c color.RGBA
+		if _, err := fmt.Sscanf(r.Fill, "#%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+			t.Fatalf("invalid fill %q: %v", r.Fill, err)
+		}
+		c.A = 0xff
+		draw.Draw(img, image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func TestEncodeFormatsMatch(t *testing.T) {
+	const scale = 8
+	encode := func(f Format) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := Encode(&buf, f, testPattern, testColorMap, scale); err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		return buf.Bytes()
+	}
+	want, err := png.Decode(bytes.NewReader(encode(PNG)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gifImg, err := gif.Decode(bytes.NewReader(encode(GIF)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jpegImg, err := jpeg.Decode(bytes.NewReader(encode(JPEG)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svgImg := decodeSVG(t, encode(SVG), scale)
+
+	for _, img := range []image.Image{gifImg, jpegImg} {
+		if img.Bounds() != want.Bounds() {
+			t.Fatalf("bounds = %v, want %v", img.Bounds(), want.Bounds())
+		}
+	}
+	// Compare at the center of each cell; JPEG is lossy around cell edges.
+	for y := range testPattern {
+		for x := range testPattern[y] {
+			px, py := x*scale+scale/2, y*scale+scale/2
+			w := want.At(px, py)
+			if got := gifImg.At(px, py); !sameColor(got, w, 0) {
+				t.Errorf("gif (%d, %d) = %v, want %v", x, y, got, w)
+			}
+			if got := jpegImg.At(px, py); !sameColor(got, w, 8) {
+				t.Errorf("jpeg (%d, %d) = %v, want %v", x, y, got, w)
+			}
+			if got := svgImg.At(x, y); !sameColor(got, w, 0) {
+				t.Errorf("svg (%d, %d) = %v, want %v", x, y, got, w)
+			}
+		}
+	}
+}
+
+func sameColor(a, b color.Color, tolerance int) bool {
+	ar, ag, ab, _ := a.RGBA()
+	br, bg, bb, _ := b.RGBA()
+	diff := func(x, y uint32) bool {
+		d := int(x>>8) - int(y>>8)
+		return d <= tolerance && -d <= tolerance
+	}
+	return diff(ar, br) && diff(ag, bg) && diff(ab, bb)
+}
+
+func TestWriteSVGMergesRuns(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSVG(&buf, [][]int{{1, 1, 1, 2}}, testColorMap, 4); err != nil {
+		t.Fatal(err)
+	}
+	want := `<svg xmlns="http://www.w3.org/2000/svg" width="16" height="4" viewBox="0 0 4 1" shape-rendering="crispEdges">` +
+		`<rect x="0" y="0" width="3" height="1" fill="#e4572e"/>` +
+		`<rect x="3" y="0" width="1" height="1" fill="#29335c"/></svg>` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestWriteSVGMatchesGenerateSVG checks that WriteSVG draws the same cells as syumaigen.GenerateSVG in less space.
+func TestWriteSVGMatchesGenerateSVG(t *testing.T) {
+	const scale = 4
+	var buf bytes.Buffer
+	if err := WriteSVG(&buf, syumaigen.Pattern, syumaigen.DefaultColorMap, scale); err != nil {
+		t.Fatal(err)
+	}
+	got := decodeSVG(t, buf.Bytes(), scale)
+
+	r, err := syumaigen.GenerateSVG(syumaigen.Pattern, syumaigen.DefaultColorMap, scale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirs, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Rects []struct {
+			X     int    `xml:"x,attr"`
+			Y     int    `xml:"y,attr"`
+			Style string `xml:"style,attr"`
+		} `xml:"rect"`
+	}
+	if err := xml.Unmarshal(theirs, &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := image.NewRGBA(got.Bounds())
+	for _, r := range doc.Rects {
+		c := color.RGBA{A: 0xff}
+		if _, err := fmt.Sscanf(r.Style, "fill: #%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+			t.Fatalf("invalid style %q: %v", r.Style, err)
+		}
+		want.Set(r.X/scale, r.Y/scale, c)
+	}
+	for y := range syumaigen.Pattern {
+		for x := range syumaigen.Pattern[y] {
+			if g, w := got.At(x, y), want.At(x, y); g != w {
+				t.Errorf("(%d, %d) = %v, want %v", x, y, g, w)
+			}
+		}
+	}
+	if buf.Len() >= len(theirs) {
+		t.Errorf("WriteSVG wrote %d bytes, GenerateSVG %d", buf.Len(), len(theirs))
+	}
+}
@@ -0,0 +1,155 @@
+package avatar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/syumai/syumaigen"
+)
+
+// Handler serves avatars generated from Pattern.
+//
+// ServeHTTP serves a single avatar. Query parameters are parsed by ParseOptions
+// and the format is selected by NegotiateFormat. Animated and Sprite serve
+// the other endpoints.
+// Deterministic responses get an ETag and are stored in Cache, so repeated
+// requests skip generation entirely.
+type Handler struct {
+	// Pattern is the base pattern, such as syumaigen.Pattern.
+	Pattern [][]int
+	// ColorMap returns the color map for a color code, such as syumaigen.GenerateColorMapByColorCode.
+	ColorMap func(colorCode string) syumaigen.ColorMap
+	// Cache is optional.
+	Cache Cache
+	// Background runs f after the response is sent, such as cloudflare.WaitUntil.
+	// If nil, f runs in a new goroutine.
+	Background func(f func())
+}
+
+var bufPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// encodeFunc writes a generated response body.
+// It is only returned after all validation, so it can only fail by a write error.
+type encodeFunc func(w io.Writer) error
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Vary", "Accept")
+	q := req.URL.Query()
+	format, err := NegotiateFormat(q.Get("format"), req.Header.Get("Accept"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts, err := ParseOptions(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	colorCode := opts.ColorCode()
+	var key string
+	if opts.Deterministic() {
+		key = CacheKey(colorCode, opts, format)
+	}
+	h.serve(w, req, key, format.ContentType(), func() (encodeFunc, error) {
+		img, err := h.image(colorCode, opts)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer) error { return img.Encode(w, format) }, nil
+	})
+}
+
+// image renders the avatar of colorCode with opts.
+func (h *Handler) image(colorCode string, opts Options) (*Image, error) {
+	pattern, err := SelectPattern(h.Pattern, opts.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return New(pattern, h.ColorMap(colorCode), opts.Scale)
+}
+
+// serve writes the response generated by generate.
+// If key is not empty, the response is deterministic and cached by key.
+// Errors of generate are sent as 500 responses.
+func (h *Handler) serve(w http.ResponseWriter, req *http.Request, key, contentType string, generate func() (encodeFunc, error)) {
+	if key != "" {
+		etag := `"` + key + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		if etagMatch(req.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if h.Cache != nil {
+			data, err := h.Cache.Get(req.Context(), key)
+			switch {
+			case err == nil:
+				w.Header().Set("Content-Type", contentType)
+				w.Write(data)
+				return
+			case !errors.Is(err, ErrCacheMiss):
+				log.Printf("avatar: cache get %s: %v", key, err)
+			}
+		}
+	} else {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+
+	encode, err := generate()
+	if err != nil {
+		w.Header().Del("ETag")
+		w.Header().Del("Cache-Control")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	var out io.Writer = w
+	var buf *bytes.Buffer
+	if key != "" && h.Cache != nil {
+		buf = bufPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		defer bufPool.Put(buf)
+		out = io.MultiWriter(w, buf)
+	}
+	// The header is sent by the first write, so an error here can only be logged.
+	if err := encode(out); err != nil {
+		log.Printf("avatar: encode %s: %v", contentType, err)
+		return
+	}
+	if buf != nil {
+		data := bytes.Clone(buf.Bytes())
+		h.background(func() {
+			if err := h.Cache.Put(context.Background(), key, contentType, data); err != nil {
+				log.Printf("avatar: cache put %s: %v", key, err)
+			}
+		})
+	}
+}
+
+func (h *Handler) background(f func()) {
+	if h.Background != nil {
+		h.Background(f)
+		return
+	}
+	go f()
+}
+
+// etagMatch reports whether the If-None-Match header value matches etag.
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, v := range strings.Split(ifNoneMatch, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package avatar
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/syumai/syumaigen"
+)
+
+type countingHandler struct {
+	*Handler
+	generated int
+}
+
+func newTestHandler(cache Cache) *countingHandler {
+	h := &countingHandler{}
+	h.Handler = &Handler{
+		Pattern: testPattern,
+		ColorMap: func(string) syumaigen.ColorMap {
+			h.generated++
+			return testColorMap
+		},
+		Cache:      cache,
+		Background: func(f func()) { f() },
+	}
+	return h
+}
+
+func serve(h http.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlerCache(t *testing.T) {
+	cache := &MemoryCache{}
+	h := newTestHandler(cache)
+
+	first := serve(h, "/generate?seed=alice&scale=2", nil)
+	if first.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", first.Code, first.Body)
+	}
+	if got := first.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag for a seeded avatar")
+	}
+	want := new(bytes.Buffer)
+	if err := WritePNG(want, testPattern, testColorMap, 2); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Body.Bytes(), want.Bytes()) {
+		t.Error("streamed body differs from WritePNG")
+	}
+
+	second := serve(h, "/generate?seed=alice&scale=2", nil)
+	if h.generated != 1 {
+		t.Errorf("generated %d times, want 1", h.generated)
+	}
+	if !bytes.Equal(second.Body.Bytes(), first.Body.Bytes()) {
+		t.Error("cached body differs")
+	}
+	if got := second.Header().Get("ETag"); got != etag {
+		t.Errorf("ETag = %q, want %q", got, etag)
+	}
+
+	notModified := serve(h, "/generate?seed=alice&scale=2", http.Header{"If-None-Match": {`"other", ` + etag}})
+	if notModified.Code != http.StatusNotModified || notModified.Body.Len() != 0 {
+		t.Errorf("status = %d, body = %d bytes; want 304 without body", notModified.Code, notModified.Body.Len())
+	}
+
+	svg := serve(h, "/generate?seed=alice&scale=2&format=svg", nil)
+	if got := svg.Header().Get("ETag"); got == etag {
+		t.Error("formats must not share an ETag")
+	}
+	if h.generated != 2 {
+		t.Errorf("generated %d times, want 2", h.generated)
+	}
+}
+
+func TestHandlerRandom(t *testing.T) {
+	h := newTestHandler(&MemoryCache{})
+	rec := serve(h, "/generate", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if got := rec.Header().Get("ETag"); got != "" {
+		t.Errorf("random avatar has ETag %q", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept" {
+		t.Errorf("Vary = %q", got)
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h := newTestHandler(nil)
+	if rec := serve(h, "/generate?scale=100", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad scale: status = %d", rec.Code)
+	}
+	if rec := serve(h, "/generate?format=bmp", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad format: status = %d", rec.Code)
+	}
+	h.Pattern = [][]int{{0, 9}}
+	rec := serve(h, "/generate?seed=alice", nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("broken color map: status = %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got == "image/png" {
+		t.Error("error response has an image Content-Type")
+	}
+}
+
+func TestHandlerGenerateImageError(t *testing.T) {
+	for _, target := range []string{
+		"/generate?seed=alice",
+		"/generate?seed=alice&format=svg",
+		"/generate/animated?seed=alice",
+		"/sprite?n=2",
+	} {
+		t.Run(target, func(t *testing.T) {
+			cache := &MemoryCache{}
+			h := newTestHandler(cache)
+			// syumaigen.GenerateImage fails on a color missing from the color map.
+			h.Pattern = [][]int{{0, 9}}
+			mux := http.NewServeMux()
+			mux.Handle("/generate", h)
+			mux.HandleFunc("/generate/animated", h.Animated)
+			mux.HandleFunc("/sprite", h.Sprite)
+
+			rec := serve(mux, target, nil)
+			if rec.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+			}
+			if got := rec.Body.String(); !strings.Contains(got, "color not found: 9") {
+				t.Errorf("body = %q, want the GenerateImage error", got)
+			}
+			for _, name := range []string{"ETag", "Cache-Control"} {
+				if got := rec.Header().Get(name); got != "" {
+					t.Errorf("%s = %q on an error response", name, got)
+				}
+			}
+			if len(cache.entries) != 0 {
+				t.Errorf("cached %d entries for a failed generation", len(cache.entries))
+			}
+		})
+	}
+}
+
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := &MemoryCache{}, &MemoryCache{}
+	c := TieredCache{l1, l2}
+	if _, err := c.Get(ctx, "k"); err != ErrCacheMiss {
+		t.Fatalf("err = %v, want ErrCacheMiss", err)
+	}
+	l2.Put(ctx, "k", "image/png", []byte("data"))
+	if got, err := c.Get(ctx, "k"); err != nil || string(got) != "data" {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+	if got, err := l1.Get(ctx, "k"); err != nil || string(got) != "data" {
+		t.Errorf("first tier was not filled: %q, %v", got, err)
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	ctx := context.Background()
+	c := &MemoryCache{Size: 2}
+	for _, k := range []string{"a", "b", "c"} {
+		c.Put(ctx, k, "", []byte(k))
+	}
+	if _, err := c.Get(ctx, "a"); err != ErrCacheMiss {
+		t.Errorf("oldest entry was not evicted")
+	}
+	if _, err := c.Get(ctx, "c"); err != nil {
+		t.Errorf("newest entry: %v", err)
+	}
+}
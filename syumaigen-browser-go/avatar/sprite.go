@@ -0,0 +1,213 @@
+package avatar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/syumai/syumaigen"
+)
+
+const (
+	DefaultSpriteCount = 16
+	MaxSpriteCount     = 64
+	MaxSpriteColumns   = 16
+	// MaxSpritePixels bounds the size of a sprite sheet. The sheet is an RGBA image
+	// of 4 bytes per pixel, so it stays at 16 MB, far below the memory limit of Workers.
+	MaxSpritePixels = 4 << 20
+)
+
+// SpriteOptions are the options of a sprite sheet.
+type SpriteOptions struct {
+	// Seeds are the seeds of the avatars in the sheet, in row-major order.
+	Seeds []string
+	// Columns is the number of avatars in a row.
+	Columns int
+	Scale   int
+	Pattern string
+}
+
+// ParseSpriteOptions parses the query parameters of a sprite sheet.
+//   - seeds is a comma-separated list of seeds.
+//   - Without seeds, n seeds are made of the seed parameter followed by 1 to n, like "alice1".
+//   - columns defaults to the square root of the number of seeds rounded up.
+//   - scale and pattern are the same as ParseOptions.
+//
+// Sprite sheets never use random colors, so the image and its manifest always agree.
+func ParseSpriteOptions(q url.Values) (SpriteOptions, error) {
+	base, err := ParseOptions(url.Values{"scale": q["scale"], "pattern": q["pattern"]})
+	if err != nil {
+		return SpriteOptions{}, err
+	}
+	opts := SpriteOptions{Scale: base.Scale, Pattern: base.Pattern}
+	if s := q.Get("seeds"); s != "" {
+		opts.Seeds = strings.Split(s, ",")
+		if len(opts.Seeds) > MaxSpriteCount {
+			return SpriteOptions{}, fmt.Errorf("too many seeds: %d > %d", len(opts.Seeds), MaxSpriteCount)
+		}
+	} else {
+		n, err := intParam(q, "n", DefaultSpriteCount, 1, MaxSpriteCount)
+		if err != nil {
+			return SpriteOptions{}, err
+		}
+		prefix := q.Get("seed")
+		for i := range n {
+			opts.Seeds = append(opts.Seeds, prefix+strconv.Itoa(i+1))
+		}
+	}
+	defaultColumns := min(int(math.Ceil(math.Sqrt(float64(len(opts.Seeds))))), MaxSpriteColumns)
+	opts.Columns, err = intParam(q, "columns", defaultColumns, 1, MaxSpriteColumns)
+	if err != nil {
+		return SpriteOptions{}, err
+	}
+	return opts, nil
+}
+
+// SpriteManifest describes the position of each avatar in a sprite sheet.
+type SpriteManifest struct {
+	// Image is the URL of the sprite sheet image, relative to the manifest.
+	Image      string         `json:"image,omitempty"`
+	Width      int            `json:"width"`
+	Height     int            `json:"height"`
+	Columns    int            `json:"columns"`
+	Rows       int            `json:"rows"`
+	TileWidth  int            `json:"tileWidth"`
+	TileHeight int            `json:"tileHeight"`
+	Sprites    []SpriteOffset `json:"sprites"`
+}
+
+// SpriteOffset is the position of an avatar in a sprite sheet.
+type SpriteOffset struct {
+	Seed  string `json:"seed"`
+	Color string `json:"color"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+}
+
+// NewSpriteManifest returns the manifest of the sprite sheet of opts
+// whose pattern, after selection, is patternWidth x patternHeight cells.
+func NewSpriteManifest(opts SpriteOptions, patternWidth, patternHeight int) *SpriteManifest {
+	rows := (len(opts.Seeds) + opts.Columns - 1) / opts.Columns
+	tw, th := patternWidth*opts.Scale, patternHeight*opts.Scale
+	m := &SpriteManifest{
+		Width:      min(opts.Columns, len(opts.Seeds)) * tw,
+		Height:     rows * th,
+		Columns:    opts.Columns,
+		Rows:       rows,
+		TileWidth:  tw,
+		TileHeight: th,
+		Sprites:    make([]SpriteOffset, len(opts.Seeds)),
+	}
+	for i, seed := range opts.Seeds {
+		m.Sprites[i] = SpriteOffset{
+			Seed:  seed,
+			Color: SeedColorCode(seed),
+			X:     i % opts.Columns * tw,
+			Y:     i / opts.Columns * th,
+		}
+	}
+	return m
+}
+
+// checkSize returns an error if the sprite sheet of m exceeds MaxSpritePixels.
+func (m *SpriteManifest) checkSize() error {
+	if m.Width*m.Height > MaxSpritePixels {
+		return fmt.Errorf("sprite sheet too large: %dx%d pixels exceeds %d; reduce n, seeds or scale", m.Width, m.Height, MaxSpritePixels)
+	}
+	return nil
+}
+
+// RenderSprite draws the avatars of manifest onto one image.
+func RenderSprite(pattern [][]int, colorMap func(colorCode string) syumaigen.ColorMap, m *SpriteManifest, scale int) (*image.RGBA, error) {
+	if err := m.checkSize(); err != nil {
+		return nil, fmt.Errorf("avatar: %w", err)
+	}
+	sheet := image.NewRGBA(image.Rect(0, 0, m.Width, m.Height))
+	for _, s := range m.Sprites {
+		img, err := Render(pattern, colorMap(s.Color), scale)
+		if err != nil {
+			return nil, err
+		}
+		if img.Bounds().Dx() != m.TileWidth || img.Bounds().Dy() != m.TileHeight {
+			return nil, errors.New("avatar: pattern size does not match the manifest")
+		}
+		draw.Draw(sheet, img.Bounds().Add(image.Pt(s.X, s.Y)), img, image.Point{}, draw.Src)
+	}
+	return sheet, nil
+}
+
+// Sprite serves a sprite sheet of avatars, or its manifest as JSON.
+// It takes the query parameters of ParseSpriteOptions. The format is
+// png (default), jpeg or json, chosen by the format parameter or the Accept header.
+func (h *Handler) Sprite(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Vary", "Accept")
+	q := req.URL.Query()
+	opts, err := ParseSpriteOptions(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pattern, err := SelectPattern(h.Pattern, opts.Pattern)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(pattern) == 0 || len(pattern[0]) == 0 {
+		http.Error(w, "avatar: empty pattern", http.StatusInternalServerError)
+		return
+	}
+	m := NewSpriteManifest(opts, len(pattern[0]), len(pattern))
+	// The options are checked one by one, so their product is checked here,
+	// also for the manifest, whose image would be rejected otherwise.
+	if err := m.checkSize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := q.Get("format")
+	if format == "" && acceptQuality(req.Header.Get("Accept"), "application/json") > acceptQuality(req.Header.Get("Accept"), PNG.ContentType()) {
+		format = "json"
+	}
+	if format == "json" {
+		imageQuery := url.Values{}
+		for k, v := range q {
+			imageQuery[k] = v
+		}
+		imageQuery.Set("format", string(PNG))
+		m.Image = req.URL.Path + "?" + imageQuery.Encode()
+		h.serve(w, req, cacheKey("sprite.json", m.Image), "application/json", func() (encodeFunc, error) {
+			return func(w io.Writer) error { return json.NewEncoder(w).Encode(m) }, nil
+		})
+		return
+	}
+
+	f := PNG
+	if format != "" {
+		if f, err = ParseFormat(format); err != nil || (f != PNG && f != JPEG) {
+			http.Error(w, fmt.Sprintf("sprite format must be png, jpeg or json: %q", format), http.StatusBadRequest)
+			return
+		}
+	}
+	key := cacheKey("sprite", strings.Join(opts.Seeds, "\x00"), opts.Columns, opts.Scale, opts.Pattern, f)
+	h.serve(w, req, key, f.ContentType(), func() (encodeFunc, error) {
+		sheet, err := RenderSprite(pattern, h.ColorMap, m, opts.Scale)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer) error {
+			if f == JPEG {
+				return jpeg.Encode(w, sheet, &jpeg.Options{Quality: 100})
+			}
+			return pngEncoder.Encode(w, sheet)
+		}, nil
+	})
+}
@@ -0,0 +1,144 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/syumai/syumaigen"
+)
+
+func TestParseSpriteOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    SpriteOptions
+		wantErr bool
+	}{
+		{query: "n=3&seed=a", want: SpriteOptions{Seeds: []string{"a1", "a2", "a3"}, Columns: 2, Scale: DefaultScale, Pattern: "default"}},
+		{query: "seeds=x,y&columns=1&scale=2&pattern=flip", want: SpriteOptions{Seeds: []string{"x", "y"}, Columns: 1, Scale: 2, Pattern: "flip"}},
+		{query: "n=0", wantErr: true},
+		{query: "n=65", wantErr: true},
+		{query: "columns=17", wantErr: true},
+		{query: "scale=99", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			got, err := ParseSpriteOptions(q)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("want error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if opts, _ := ParseSpriteOptions(url.Values{}); len(opts.Seeds) != DefaultSpriteCount || opts.Columns != 4 {
+		t.Errorf("defaults = %d seeds, %d columns", len(opts.Seeds), opts.Columns)
+	}
+}
+
+func TestSpriteTooLarge(t *testing.T) {
+	h := newTestHandler(nil)
+	h.Pattern = syumaigen.Pattern
+	// The largest values accepted one by one would make a 5120x5120 sheet.
+	for _, format := range []string{"png", "json"} {
+		rec := serve(http.HandlerFunc(h.Sprite), "/sprite?n=64&scale=32&format="+format, nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", format, rec.Code, http.StatusBadRequest)
+		}
+	}
+	if h.generated != 0 {
+		t.Errorf("generated %d color maps for a rejected sheet", h.generated)
+	}
+	rec := serve(http.HandlerFunc(h.Sprite), "/sprite?n=64&scale=10&format=json", nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("n=64&scale=10: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	m := &SpriteManifest{Width: 5120, Height: 5120}
+	if _, err := RenderSprite(syumaigen.Pattern, syumaigen.GenerateColorMapByColorCode, m, 32); err == nil {
+		t.Error("RenderSprite: want error for a sheet over MaxSpritePixels")
+	}
+}
+
+func TestSprite(t *testing.T) {
+	h := newTestHandler(&MemoryCache{})
+	const query = "/sprite?seeds=alice,bob,carol&columns=2&scale=3"
+
+	rec := serve(http.HandlerFunc(h.Sprite), query+"&format=json", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var m SpriteManifest
+	if err := json.Unmarshal(rec.Body.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	want := SpriteManifest{
+		Image:   "/sprite?columns=2&format=png&scale=3&seeds=alice%2Cbob%2Ccarol",
+		Width:   24,
+		Height:  24,
+		Columns: 2, Rows: 2,
+		TileWidth: 12, TileHeight: 12,
+		Sprites: []SpriteOffset{
+			{Seed: "alice", Color: SeedColorCode("alice"), X: 0, Y: 0},
+			{Seed: "bob", Color: SeedColorCode("bob"), X: 12, Y: 0},
+			{Seed: "carol", Color: SeedColorCode("carol"), X: 0, Y: 12},
+		},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("manifest = %+v, want %+v", m, want)
+	}
+	if h.generated != 0 {
+		t.Errorf("manifest generated %d images", h.generated)
+	}
+
+	// The Accept header selects the manifest too.
+	if rec := serve(http.HandlerFunc(h.Sprite), query, http.Header{"Accept": {"application/json"}}); rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Accept: application/json got %q", rec.Header().Get("Content-Type"))
+	}
+
+	rec = serve(http.HandlerFunc(h.Sprite), m.Image, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	sheet, err := png.Decode(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := sheet.Bounds(); b.Dx() != m.Width || b.Dy() != m.Height {
+		t.Fatalf("size = %v, want %dx%d", b, m.Width, m.Height)
+	}
+	// Every tile is the avatar at its offset.
+	var tile bytes.Buffer
+	if err := WritePNG(&tile, testPattern, testColorMap, 3); err != nil {
+		t.Fatal(err)
+	}
+	single, _ := png.Decode(&tile)
+	for _, s := range m.Sprites {
+		for y := range m.TileHeight {
+			for x := range m.TileWidth {
+				if got, want := sheet.At(s.X+x, s.Y+y), single.At(x, y); !sameColor(got, want, 0) {
+					t.Fatalf("%s (%d, %d) = %v, want %v", s.Seed, x, y, got, want)
+				}
+			}
+		}
+	}
+	if h.generated != 3 {
+		t.Errorf("generated %d avatars, want 3", h.generated)
+	}
+
+	if rec := serve(http.HandlerFunc(h.Sprite), query+"&format=svg", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("svg sprite: status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"syscall/js"
+
+	"github.com/syumai/workers-playground/syumaigen-browser-go/avatar"
+	"github.com/syumai/workers/cloudflare"
+	"github.com/syumai/workers/cloudflare/cache"
+)
+
+// cacheBaseURL is the URL prefix of cache keys in the Cache API. It is never fetched.
+const cacheBaseURL = "https://syumaigen-cache.invalid/"
+
+// cacheMaxAge is the max-age of avatars stored in the Cache API.
+const cacheMaxAge = 365 * 24 * 60 * 60
+
+// runsOnWorkers reports whether the program is running on Cloudflare Workers rather than in a browser.
+func runsOnWorkers() bool {
+	caches := js.Global().Get("caches")
+	return !caches.IsUndefined() && !caches.Get("default").IsUndefined()
+}
+
+// newAvatarCache returns the cache of generated avatars.
+//   - On Workers, the Cache API of the data center is backed by the AVATAR_CACHE R2 bucket if it is bound.
+//   - In a browser, the Cache Storage of the page is used if it is available.
+//   - Otherwise, avatars are kept in memory.
+func newAvatarCache() avatar.Cache {
+	if runsOnWorkers() {
+		tiers := avatar.TieredCache{&cfCache{cache: cache.New()}}
+		if bucket, err := cloudflare.NewR2Bucket("AVATAR_CACHE"); err == nil {
+			tiers = append(tiers, &r2Cache{bucket: bucket})
+		}
+		return tiers
+	}
+	if caches := js.Global().Get("caches"); !caches.IsUndefined() {
+		return &cacheStorage{caches: caches, name: "syumaigen"}
+	}
+	return &avatar.MemoryCache{Size: 256}
+}
+
+// cfCache is an avatar.Cache on the Cache API of Cloudflare Workers.
+type cfCache struct {
+	cache *cache.Cache
+}
+
+func (c *cfCache) Get(_ context.Context, key string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, cacheBaseURL+key, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.cache.Match(req, nil)
+	if errors.Is(err, cache.ErrCacheNotFound) {
+		return nil, avatar.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
+}
+
+func (c *cfCache) Put(_ context.Context, key, contentType string, data []byte) error {
+	req, err := http.NewRequest(http.MethodGet, cacheBaseURL+key, nil)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	header.Set("Cache-Control", "public, max-age="+strconv.Itoa(cacheMaxAge))
+	res := &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+	}
+	return c.cache.Put(req, res)
+}
+
+// r2Cache is an avatar.Cache on an R2 bucket.
+type r2Cache struct {
+	bucket *cloudflare.R2Bucket
+}
+
+func (c *r2Cache) Get(_ context.Context, key string) ([]byte, error) {
+	obj, err := c.bucket.Get("avatars/" + key)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, avatar.ErrCacheMiss
+	}
+	return io.ReadAll(obj.Body)
+}
+
+func (c *r2Cache) Put(_ context.Context, key, contentType string, data []byte) error {
+	_, err := c.bucket.Put("avatars/"+key, io.NopCloser(bytes.NewReader(data)), &cloudflare.R2PutOptions{
+		HTTPMetadata: cloudflare.R2HTTPMetadata{ContentType: contentType},
+	})
+	return err
+}
+
+// cacheStorage is an avatar.Cache on the Cache Storage API of a browser.
+type cacheStorage struct {
+	caches js.Value
+	name   string
+}
+
+func (c *cacheStorage) open() (js.Value, error) {
+	return await(c.caches.Call("open", c.name))
+}
+
+func (c *cacheStorage) Get(_ context.Context, key string) ([]byte, error) {
+	store, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	res, err := await(store.Call("match", cacheBaseURL+key))
+	if err != nil {
+		return nil, err
+	}
+	if res.IsUndefined() {
+		return nil, avatar.ErrCacheMiss
+	}
+	buf, err := await(res.Call("arrayBuffer"))
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, buf.Get("byteLength").Int())
+	js.CopyBytesToGo(data, js.Global().Get("Uint8Array").New(buf))
+	return data, nil
+}
+
+func (c *cacheStorage) Put(_ context.Context, key, contentType string, data []byte) error {
+	store, err := c.open()
+	if err != nil {
+		return err
+	}
+	body := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(body, data)
+	headers := js.Global().Get("Object").New()
+	if contentType != "" {
+		headers.Set("Content-Type", contentType)
+	}
+	init := js.Global().Get("Object").New()
+	init.Set("headers", headers)
+	res := js.Global().Get("Response").New(body, init)
+	_, err = await(store.Call("put", cacheBaseURL+key, res))
+	return err
+}
+
+// await waits for the promise p to settle.
+func await(p js.Value) (js.Value, error) {
+	type result struct {
+		v   js.Value
+		err error
+	}
+	ch := make(chan result, 1)
+	onFulfilled := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		ch <- result{v: args[0]}
+		return nil
+	})
+	defer onFulfilled.Release()
+	onRejected := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		ch <- result{err: fmt.Errorf("promise rejected: %s", args[0].Call("toString").String())}
+		return nil
+	})
+	defer onRejected.Release()
+	p.Call("then", onFulfilled, onRejected)
+	r := <-ch
+	return r.v, r.err
+}
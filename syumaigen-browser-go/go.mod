@@ -2,10 +2,12 @@ module github.com/syumai/workers-playground/syumaigen-browser-go
 
 go 1.24.1
 
-require github.com/syumai/workers v0.30.0
+require (
+	github.com/lucasb-eyer/go-colorful v1.2.0
+	github.com/syumai/syumaigen v0.5.0
+	github.com/syumai/workers v0.30.0
+)
 
 require (
 	github.com/ajstarks/svgo v0.0.0-20200725142600-7a3c8b57fecb // indirect
-	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
-	github.com/syumai/syumaigen v0.5.0 // indirect
 )
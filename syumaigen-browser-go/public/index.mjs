@@ -2,12 +2,48 @@ import handlers from "./build/worker.mjs";
 
 const appForm = document.getElementById("app");
 const avatar = document.getElementById("avatar");
+const manifest = document.getElementById("manifest");
+
+// Parameters which are not accepted by each endpoint.
+const ignoredParams = {
+  "/generate": ["n"],
+  "/generate/animated": ["n", "format"],
+  "/sprite": [],
+};
+
+async function fetchOK(url) {
+  const res = await handlers.fetch(new Request(url));
+  if (!res.ok) {
+    throw new Error(await res.text());
+  }
+  return res;
+}
 
 appForm.addEventListener("submit", async (e) => {
   e.preventDefault();
-  const req = new Request("/generate");
-  const res = await handlers.fetch(req);
-  const blob = await res.blob();
-  const url = URL.createObjectURL(blob);
-  avatar.src = url;
+  const data = new FormData(appForm);
+  const endpoint = data.get("endpoint");
+  data.delete("endpoint");
+  const params = new URLSearchParams();
+  for (const [key, value] of data) {
+    if (value !== "" && !ignoredParams[endpoint].includes(key)) {
+      params.set(key, value);
+    }
+  }
+  if (endpoint === "/sprite" && !["png", "jpeg"].includes(params.get("format"))) {
+    params.delete("format");
+  }
+  try {
+    const res = await fetchOK(`${endpoint}?${params}`);
+    const blob = await res.blob();
+    avatar.src = URL.createObjectURL(blob);
+    manifest.textContent = "";
+    if (endpoint === "/sprite") {
+      params.set("format", "json");
+      const res = await fetchOK(`${endpoint}?${params}`);
+      manifest.textContent = JSON.stringify(await res.json(), null, 2);
+    }
+  } catch (err) {
+    alert(err.message);
+  }
 });
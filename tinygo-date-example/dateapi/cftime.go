@@ -0,0 +1,81 @@
+package dateapi
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// CFTime is the response of /cf-time.
+//
+// Workers freeze the clock while code runs: Date.now() and performance.now(), which back time.Now on Wasm,
+// only advance after I/O, to mitigate timing attacks. So CPUElapsed is zero on Workers,
+// and IOElapsed includes the CPU time before the I/O.
+//   - https://developers.cloudflare.com/workers/reference/security-model/#step-1-disallow-timers-and-multi-threading
+type CFTime struct {
+	Start    string `json:"start"`
+	AfterCPU string `json:"after_cpu"`
+	AfterIO  string `json:"after_io"`
+	// Iterations is the number of iterations of the CPU work.
+	Iterations int    `json:"iterations"`
+	CPUElapsed string `json:"cpu_elapsed"`
+	IOElapsed  string `json:"io_elapsed"`
+	// Frozen is whether the clock did not advance during the CPU work.
+	Frozen bool `json:"frozen"`
+}
+
+// maxIterations bounds n of /cf-time. A million iterations take a few milliseconds
+// of CPU time in Wasm, so a request stays far from the CPU time limit of Workers.
+const maxIterations = 2_000_000
+
+// cfTime handles /cf-time?n=. It reads the clock before and after n iterations of CPU work, and after I/O.
+func (a *api) cfTime(w http.ResponseWriter, req *http.Request) {
+	n, err := intParam(req, "n", 1_000_000, 1, maxIterations)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	start := a.Now()
+	x := spin(n)
+	afterCPU := a.Now()
+	// Keeps the CPU work from being optimized away.
+	runtime.KeepAlive(x)
+	if err := a.IO(req.Context()); err != nil {
+		writeError(w, err)
+		return
+	}
+	afterIO := a.Now()
+	writeJSON(w, &CFTime{
+		Start:      start.Format(time.RFC3339Nano),
+		AfterCPU:   afterCPU.Format(time.RFC3339Nano),
+		AfterIO:    afterIO.Format(time.RFC3339Nano),
+		Iterations: n,
+		CPUElapsed: afterCPU.Sub(start).String(),
+		IOElapsed:  afterIO.Sub(afterCPU).String(),
+		Frozen:     afterCPU.Equal(start),
+	})
+}
+
+// spin does n iterations of xorshift without I/O.
+func spin(n int) uint64 {
+	x := uint64(88172645463325252)
+	for i := 0; i < n; i++ {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+	}
+	return x
+}
+
+// timerIO waits for a timer, which is I/O for the Workers runtime.
+func timerIO(ctx context.Context) error {
+	t := time.NewTimer(time.Millisecond)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,290 @@
+// Package dateapi is a JSON API to format, parse and compare times on Wasm.
+//
+// It does not depend on the Workers runtime, so it can be tested natively.
+package dateapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/syumai/workers-playground/tinygo-date-example/tz"
+)
+
+// Options configures the API.
+type Options struct {
+	// Sources resolve time zone names in order.
+	Sources tz.Chain
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+	// IO performs the I/O of /cf-time. It defaults to waiting for a short timer.
+	IO func(ctx context.Context) error
+}
+
+type api struct {
+	Options
+}
+
+// New returns a handler serving /now, /parse, /diff and /cf-time.
+func New(opts Options) http.Handler {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.IO == nil {
+		opts.IO = timerIO
+	}
+	a := &api{opts}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/now", a.now)
+	mux.HandleFunc("/parse", a.parse)
+	mux.HandleFunc("/diff", a.diff)
+	mux.HandleFunc("/cf-time", a.cfTime)
+	return mux
+}
+
+// Time is a formatted time in a time zone.
+type Time struct {
+	Time         string `json:"time"`
+	Format       string `json:"format"`
+	Zone         string `json:"zone"`
+	Abbreviation string `json:"abbreviation"`
+	// Offset is in seconds east of UTC.
+	Offset int `json:"offset"`
+	// Source is the name of the source which resolved the zone.
+	Source string `json:"source,omitempty"`
+}
+
+// newTime formats t in zone, which is resolved at t.
+func newTime(t time.Time, f Format, zone string, sources tz.Chain) (*Time, error) {
+	loc, src, err := resolve(sources, zone, t)
+	if err != nil {
+		return nil, err
+	}
+	t = t.In(loc)
+	abbr, offset := t.Zone()
+	res := &Time{
+		Time:         f.Format(t),
+		Format:       f.Name,
+		Zone:         "UTC",
+		Abbreviation: abbr,
+		Offset:       offset,
+	}
+	if src != nil {
+		res.Zone = zone
+		res.Source = src.Name()
+	}
+	return res, nil
+}
+
+// now handles /now?format=&tz=&source=.
+func (a *api) now(w http.ResponseWriter, req *http.Request) {
+	now := a.Now()
+	q := req.URL.Query()
+	f, err := ParseFormat(q.Get("format"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sources, err := a.sources(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, err := newTime(now, f, q.Get("tz"), sources)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, res)
+}
+
+// ParseResult is the response of /parse.
+type ParseResult struct {
+	Input string `json:"input"`
+	Time
+}
+
+// parse handles /parse?value=&from=&from_tz=&to=&to_tz=&source=.
+func (a *api) parse(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	value := q.Get("value")
+	if value == "" {
+		writeError(w, fmt.Errorf("%w: value is required", errBadRequest))
+		return
+	}
+	from, err := ParseFormat(q.Get("from"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	to, err := ParseFormat(q.Get("to"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sources, err := a.sources(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	t, err := parseIn(sources, from, value, q.Get("from_tz"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, err := newTime(t, to, q.Get("to_tz"), sources)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, &ParseResult{Input: value, Time: *res})
+}
+
+// DiffResult is the response of /diff.
+type DiffResult struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Duration is the elapsed time, such as "26h3m0s".
+	Duration string `json:"duration"`
+	// Seconds is the elapsed time in seconds.
+	Seconds float64 `json:"seconds"`
+	// Calendar is the difference between the wall clocks in the zone.
+	Calendar Calendar `json:"calendar"`
+	// Negative is whether to is before from. Calendar is positive even then.
+	Negative bool   `json:"negative"`
+	Zone     string `json:"zone"`
+}
+
+// diff handles /diff?from=&to=&format=&tz=&source=. A missing from or to is the current time.
+// Times more than a time.Duration (about 292 years) apart are rejected.
+func (a *api) diff(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	f, err := ParseFormat(q.Get("format"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sources, err := a.sources(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	zone := q.Get("tz")
+	now := a.Now()
+	var ts [2]time.Time
+	for i, key := range []string{"from", "to"} {
+		value := q.Get(key)
+		if value == "" {
+			ts[i] = now
+			continue
+		}
+		if ts[i], err = parseIn(sources, f, value, zone); err != nil {
+			writeError(w, fmt.Errorf("%s: %w", key, err))
+			return
+		}
+	}
+	// Each time is in the zone at itself, so that the wall clocks are right across DST transitions.
+	for i, t := range ts {
+		loc, _, err := resolve(sources, zone, t)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		ts[i] = t.In(loc)
+	}
+	d := ts[1].Sub(ts[0])
+	// Sub saturates at the range of time.Duration.
+	if !ts[0].Add(d).Equal(ts[1]) {
+		writeError(w, fmt.Errorf("%w: from and to must be less than about 292 years apart", errBadRequest))
+		return
+	}
+	cal, negative := CalendarDiff(ts[0], ts[1])
+	if zone == "" {
+		zone = "UTC"
+	}
+	writeJSON(w, &DiffResult{
+		From:     f.Format(ts[0]),
+		To:       f.Format(ts[1]),
+		Duration: d.String(),
+		Seconds:  d.Seconds(),
+		Calendar: cal,
+		Negative: negative,
+		Zone:     zone,
+	})
+}
+
+// sources returns the sources selected by the source parameter, or all of them.
+func (a *api) sources(req *http.Request) (tz.Chain, error) {
+	name := req.URL.Query().Get("source")
+	if name == "" {
+		return a.Sources, nil
+	}
+	src, ok := tz.Lookup(a.Sources, name)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown source: %s", errBadRequest, name)
+	}
+	return tz.Chain{src}, nil
+}
+
+// resolve resolves zone at t. An empty zone is UTC and has no source.
+func resolve(sources tz.Chain, zone string, t time.Time) (*time.Location, tz.Source, error) {
+	if zone == "" {
+		return time.UTC, nil, nil
+	}
+	return sources.Resolve(zone, t)
+}
+
+// parseIn parses value in zone.
+// The zone is resolved again at the parsed time, as a source may return a location only valid around the given time.
+func parseIn(sources tz.Chain, f Format, value, zone string) (time.Time, error) {
+	t, err := f.Parse(value, time.UTC)
+	if err != nil || zone == "" {
+		return t, err
+	}
+	for i := 0; i < 2; i++ {
+		loc, _, err := resolve(sources, zone, t)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if t, err = f.Parse(value, loc); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return t, nil
+}
+
+var errBadRequest = errors.New("bad request")
+
+// writeError writes err as JSON with the status for it.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errBadRequest), errors.Is(err, ErrFormat), errors.Is(err, tz.ErrUnknownZone):
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// intParam returns the integer parameter key in [min, max], or def if it is missing.
+func intParam(req *http.Request, key string, def, min, max int) (int, error) {
+	s := req.URL.Query().Get(key)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%w: %s must be an integer in [%d, %d]", errBadRequest, key, min, max)
+	}
+	return n, nil
+}
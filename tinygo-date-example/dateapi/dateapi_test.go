@@ -0,0 +1,198 @@
+package dateapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syumai/workers-playground/tinygo-date-example/tz"
+)
+
+var testNow = time.Date(2024, 1, 1, 14, 45, 50, 945_000_000, time.UTC)
+
+func newTestServer(t *testing.T, opts Options) *httptest.Server {
+	t.Helper()
+	if opts.Sources == nil {
+		opts.Sources = tz.Chain{tz.Table{}}
+	}
+	if opts.Now == nil {
+		opts.Now = func() time.Time { return testNow }
+	}
+	srv := httptest.NewServer(New(opts))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// get requests path and decodes the JSON response into v.
+func get(t *testing.T, srv *httptest.Server, path string, wantStatus int, v any) {
+	t.Helper()
+	res, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != wantStatus {
+		t.Fatalf("GET %s: status %d, want %d", path, res.StatusCode, wantStatus)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("GET %s: Content-Type %q", path, ct)
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+}
+
+func TestNow(t *testing.T) {
+	srv := newTestServer(t, Options{})
+	tests := []struct {
+		path string
+		want Time
+	}{
+		{"/now", Time{Time: "2024-01-01T14:45:50Z", Format: "rfc3339", Zone: "UTC", Abbreviation: "UTC"}},
+		{"/now?format=unix", Time{Time: "1704120350", Format: "unix", Zone: "UTC", Abbreviation: "UTC"}},
+		{"/now?format=rfc1123&tz=Asia/Tokyo", Time{Time: "Mon, 01 Jan 2024 23:45:50 JST", Format: "rfc1123", Zone: "Asia/Tokyo", Abbreviation: "JST", Offset: 9 * 3600, Source: "table"}},
+		{"/now?format=15:04&tz=America/New_York&source=table", Time{Time: "09:45", Format: "15:04", Zone: "America/New_York", Abbreviation: "EST", Offset: -5 * 3600, Source: "table"}},
+	}
+	for _, tt := range tests {
+		var got Time
+		get(t, srv, tt.path, http.StatusOK, &got)
+		if got != tt.want {
+			t.Errorf("GET %s = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	srv := newTestServer(t, Options{})
+	tests := []struct {
+		path string
+		want ParseResult
+	}{
+		{
+			"/parse?value=2024-07-01T09:00:00%2B09:00&to=rfc1123z&to_tz=Europe/London",
+			ParseResult{Input: "2024-07-01T09:00:00+09:00", Time: Time{Time: "Mon, 01 Jul 2024 01:00:00 +0100", Format: "rfc1123z", Zone: "Europe/London", Abbreviation: "BST", Offset: 3600, Source: "table"}},
+		},
+		{
+			"/parse?value=1704120350&from=unix",
+			ParseResult{Input: "1704120350", Time: Time{Time: "2024-01-01T14:45:50Z", Format: "rfc3339", Zone: "UTC", Abbreviation: "UTC"}},
+		},
+		// A value without offset is in from_tz, here in DST.
+		{
+			"/parse?value=2024-07-01+12:00:00&from=datetime&from_tz=America/New_York&to=unix",
+			ParseResult{Input: "2024-07-01 12:00:00", Time: Time{Time: "1719849600", Format: "unix", Zone: "UTC", Abbreviation: "UTC"}},
+		},
+	}
+	for _, tt := range tests {
+		var got ParseResult
+		get(t, srv, tt.path, http.StatusOK, &got)
+		if got != tt.want {
+			t.Errorf("GET %s = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	srv := newTestServer(t, Options{})
+	tests := []struct {
+		path string
+		want DiffResult
+	}{
+		{
+			"/diff?from=2024-01-01T00:00:00Z&to=2024-03-02T01:30:00Z",
+			DiffResult{From: "2024-01-01T00:00:00Z", To: "2024-03-02T01:30:00Z", Duration: "1465h30m0s", Seconds: 5275800, Calendar: Calendar{Months: 2, Days: 1, Hours: 1, Minutes: 30}, Zone: "UTC"},
+		},
+		// The current time is used for a missing to.
+		{
+			"/diff?from=2024-01-02T14:45:50.945Z",
+			DiffResult{From: "2024-01-02T14:45:50Z", To: "2024-01-01T14:45:50Z", Duration: "-24h0m0s", Seconds: -86400, Calendar: Calendar{Days: 1}, Negative: true, Zone: "UTC"},
+		},
+		// A day of the wall clock across the start of DST is 23 hours.
+		{
+			"/diff?from=2025-03-08+12:00:00&to=2025-03-09+12:00:00&format=datetime&tz=America/New_York",
+			DiffResult{From: "2025-03-08 12:00:00", To: "2025-03-09 12:00:00", Duration: "23h0m0s", Seconds: 82800, Calendar: Calendar{Days: 1}, Zone: "America/New_York"},
+		},
+	}
+	for _, tt := range tests {
+		var got DiffResult
+		get(t, srv, tt.path, http.StatusOK, &got)
+		if got != tt.want {
+			t.Errorf("GET %s = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestBadRequest(t *testing.T) {
+	srv := newTestServer(t, Options{})
+	for _, path := range []string{
+		"/now?format=iso",
+		"/now?tz=Mars/Olympus_Mons",
+		"/now?tz=Asia/Tokyo&source=intl",
+		"/parse",
+		"/parse?value=yesterday",
+		"/diff?from=2024-01-01",
+		"/diff?from=1700-01-01T00:00:00Z&to=2024-01-01T00:00:00Z",
+		"/cf-time?n=0",
+		"/cf-time?n=2000001",
+	} {
+		var got map[string]string
+		get(t, srv, path, http.StatusBadRequest, &got)
+		if got["error"] == "" {
+			t.Errorf("GET %s: no error message: %v", path, got)
+		}
+	}
+}
+
+// frozenClock is a clock of Workers, which only advances after I/O.
+type frozenClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *frozenClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *frozenClock) IO(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(5 * time.Millisecond)
+	return nil
+}
+
+func TestCFTime(t *testing.T) {
+	clock := &frozenClock{t: testNow}
+	srv := newTestServer(t, Options{Now: clock.Now, IO: clock.IO})
+	var got CFTime
+	get(t, srv, "/cf-time?n=1000", http.StatusOK, &got)
+	want := CFTime{
+		Start:      "2024-01-01T14:45:50.945Z",
+		AfterCPU:   "2024-01-01T14:45:50.945Z",
+		AfterIO:    "2024-01-01T14:45:50.95Z",
+		Iterations: 1000,
+		CPUElapsed: "0s",
+		IOElapsed:  "5ms",
+		Frozen:     true,
+	}
+	if got != want {
+		t.Errorf("GET /cf-time = %+v, want %+v", got, want)
+	}
+}
+
+// TestCFTimeNative shows that the clock advances during CPU work outside Workers.
+func TestCFTimeNative(t *testing.T) {
+	srv := newTestServer(t, Options{Now: time.Now})
+	var got CFTime
+	get(t, srv, "/cf-time?n=2000000", http.StatusOK, &got)
+	if got.Frozen {
+		t.Errorf("GET /cf-time = %+v, want the clock to advance", got)
+	}
+	if d, err := time.ParseDuration(got.IOElapsed); err != nil || d < time.Millisecond {
+		t.Errorf("io_elapsed = %q, want at least 1ms", got.IOElapsed)
+	}
+}
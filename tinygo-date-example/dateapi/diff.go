@@ -0,0 +1,59 @@
+package dateapi
+
+import "time"
+
+// Calendar is a difference between two times in calendar units.
+type Calendar struct {
+	Years   int `json:"years"`
+	Months  int `json:"months"`
+	Days    int `json:"days"`
+	Hours   int `json:"hours"`
+	Minutes int `json:"minutes"`
+	Seconds int `json:"seconds"`
+}
+
+// CalendarDiff returns the difference from a to b in calendar units between their wall clocks,
+// and whether b is before a. The difference is always positive.
+//
+// Months are counted first, clamping the day to the end of shorter months,
+// so that January 31 to February 28 is one month and January 31 to March 1 is one month and one day.
+// Wall clocks are compared, so a day is 24 hours of the wall clock even across DST transitions.
+func CalendarDiff(a, b time.Time) (c Calendar, negative bool) {
+	from, to := wall(a), wall(b)
+	if to.Before(from) {
+		from, to = to, from
+		negative = true
+	}
+	months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+	for months > 0 && addMonths(from, months).After(to) {
+		months--
+	}
+	rest := to.Sub(addMonths(from, months))
+	c.Years, c.Months = months/12, months%12
+	c.Days = int(rest / (24 * time.Hour))
+	rest %= 24 * time.Hour
+	c.Hours = int(rest / time.Hour)
+	rest %= time.Hour
+	c.Minutes = int(rest / time.Minute)
+	rest %= time.Minute
+	c.Seconds = int(rest / time.Second)
+	return c, negative
+}
+
+// wall returns the wall clock of t in UTC, where every day has 24 hours.
+func wall(t time.Time) time.Time {
+	y, m, d := t.Date()
+	h, min, s := t.Clock()
+	return time.Date(y, m, d, h, min, s, t.Nanosecond(), time.UTC)
+}
+
+// addMonths adds n months to t, clamping the day to the end of the month.
+func addMonths(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	h, min, s := t.Clock()
+	return time.Date(first.Year(), first.Month(), d, h, min, s, t.Nanosecond(), time.UTC)
+}
@@ -0,0 +1,119 @@
+package dateapi
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrFormat is returned for an unknown format or a value not matching the format.
+var ErrFormat = errors.New("dateapi: invalid format")
+
+// Format is a named format such as "rfc3339" or "unix", or a custom layout of the time package.
+type Format struct {
+	// Name is the name of the format, or the layout of a custom format.
+	Name string
+	// Layout is the layout of the time package. It is empty for Unix time formats.
+	Layout string
+	// unit is the unit of a Unix time format.
+	unit time.Duration
+}
+
+var namedLayouts = map[string]string{
+	"ansic":       time.ANSIC,
+	"unixdate":    time.UnixDate,
+	"rubydate":    time.RubyDate,
+	"rfc822":      time.RFC822,
+	"rfc822z":     time.RFC822Z,
+	"rfc850":      time.RFC850,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"kitchen":     time.Kitchen,
+	"datetime":    time.DateTime,
+	"dateonly":    time.DateOnly,
+	"timeonly":    time.TimeOnly,
+}
+
+var unixUnits = map[string]time.Duration{
+	"unix":      time.Second,
+	"unixmilli": time.Millisecond,
+	"unixmicro": time.Microsecond,
+	"unixnano":  time.Nanosecond,
+}
+
+// DefaultFormat is the format used when none is given.
+var DefaultFormat = Format{Name: "rfc3339", Layout: time.RFC3339}
+
+// ParseFormat returns the format named s, case-insensitively, or a custom format with s as the layout.
+// An empty s is DefaultFormat.
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return DefaultFormat, nil
+	}
+	name := strings.ToLower(s)
+	if layout, ok := namedLayouts[name]; ok {
+		return Format{Name: name, Layout: layout}, nil
+	}
+	if unit, ok := unixUnits[name]; ok {
+		return Format{Name: name, unit: unit}, nil
+	}
+	// A layout without any element formats every time as itself.
+	if time.Date(2001, 11, 22, 10, 33, 44, 0, time.UTC).Format(s) == s {
+		return Format{}, fmt.Errorf("%w: %q is neither a format name nor a layout", ErrFormat, s)
+	}
+	return Format{Name: s, Layout: s}, nil
+}
+
+// Format formats t.
+func (f Format) Format(t time.Time) string {
+	if f.unit != 0 {
+		return strconv.FormatInt(unixIn(t, f.unit), 10)
+	}
+	return t.Format(f.Layout)
+}
+
+// Parse parses value. A value without a time zone offset is in loc.
+func (f Format) Parse(value string, loc *time.Location) (time.Time, error) {
+	if f.unit != 0 {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %q is not %s time", ErrFormat, value, f.Name)
+		}
+		return fromUnix(n, f.unit).In(loc), nil
+	}
+	t, err := time.ParseInLocation(f.Layout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	return t, nil
+}
+
+// fromUnix returns the time of n units since the Unix epoch.
+// Multiplying n by unit as a time.Duration would overflow for times outside the years 1678 to 2262.
+func fromUnix(n int64, unit time.Duration) time.Time {
+	switch unit {
+	case time.Second:
+		return time.Unix(n, 0)
+	case time.Millisecond:
+		return time.UnixMilli(n)
+	case time.Microsecond:
+		return time.UnixMicro(n)
+	}
+	return time.Unix(0, n)
+}
+
+func unixIn(t time.Time, unit time.Duration) int64 {
+	switch unit {
+	case time.Second:
+		return t.Unix()
+	case time.Millisecond:
+		return t.UnixMilli()
+	case time.Microsecond:
+		return t.UnixMicro()
+	}
+	return t.UnixNano()
+}
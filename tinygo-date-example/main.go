@@ -6,12 +6,16 @@ import (
 	"time"
 
 	"github.com/syumai/workers"
+
+	"github.com/syumai/workers-playground/tinygo-date-example/dateapi"
+	"github.com/syumai/workers-playground/tinygo-date-example/tz"
 )
 
 func main() {
-	http.HandleFunc("/now", func(w http.ResponseWriter, req *http.Request) {
-		w.Write([]byte(time.Now().String()))
-	})
+	http.Handle("/", dateapi.New(dateapi.Options{
+		// Intl is a fallback for zones missing from the trimmed table.
+		Sources: tz.Chain{tz.Table{}, tz.Intl{}},
+	}))
 	http.HandleFunc("/date", func(w http.ResponseWriter, req *http.Request) {
 		ms := js.Global().Get("Date").Call("now").Float()
 		now := time.UnixMilli(int64(ms))
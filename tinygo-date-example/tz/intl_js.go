@@ -0,0 +1,92 @@
+//go:build js && wasm
+
+package tz
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall/js"
+	"time"
+)
+
+var (
+	dateClass               = js.Global().Get("Date")
+	intlDateTimeFormatClass = js.Global().Get("Intl").Get("DateTimeFormat")
+)
+
+// Intl is a Source deriving offsets from the Intl.DateTimeFormat API of the JavaScript runtime.
+// Its locations are fixed zones which are only valid at the given time.
+type Intl struct{}
+
+func (Intl) Name() string {
+	return "intl"
+}
+
+func (Intl) Zone(name string, t time.Time) (loc *time.Location, err error) {
+	if name == "" {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownZone, name)
+	}
+	// Intl.DateTimeFormat throws a RangeError for an unknown zone, which syscall/js raises as a panic.
+	defer func() {
+		if r := recover(); r != nil {
+			loc, err = nil, fmt.Errorf("%w: %q: %v", ErrUnknownZone, name, r)
+		}
+	}()
+	date := dateClass.New(t.UnixMilli())
+	offset, err := parseGMTOffset(zonePart(name, "longOffset", date))
+	if err != nil {
+		return nil, err
+	}
+	abbr := zonePart(name, "short", date)
+	// Zones without a common abbreviation in en-US are shown like "GMT+9".
+	if abbr != "UTC" && (abbr == "" || strings.HasPrefix(abbr, "GMT") || strings.HasPrefix(abbr, "UTC")) {
+		abbr = numericAbbr(offset)
+	}
+	return time.FixedZone(abbr, offset), nil
+}
+
+// zonePart formats date in the zone name and returns its timeZoneName part in style.
+func zonePart(name, style string, date js.Value) string {
+	opts := js.Global().Get("Object").New()
+	opts.Set("timeZone", name)
+	opts.Set("timeZoneName", style)
+	parts := intlDateTimeFormatClass.New("en-US", opts).Call("formatToParts", date)
+	for i := 0; i < parts.Length(); i++ {
+		if p := parts.Index(i); p.Get("type").String() == "timeZoneName" {
+			return p.Get("value").String()
+		}
+	}
+	return ""
+}
+
+// parseGMTOffset parses an offset like "GMT+09:00", "GMT-03:30" or "GMT" into seconds east of UTC.
+func parseGMTOffset(s string) (int, error) {
+	rest, ok := strings.CutPrefix(s, "GMT")
+	if !ok {
+		return 0, fmt.Errorf("tz: invalid offset %q", s)
+	}
+	if rest == "" {
+		return 0, nil
+	}
+	sign := 1
+	switch rest[0] {
+	case '+':
+	case '-':
+		sign = -1
+	default:
+		return 0, fmt.Errorf("tz: invalid offset %q", s)
+	}
+	hh, mm, _ := strings.Cut(rest[1:], ":")
+	h, err := strconv.Atoi(hh)
+	if err != nil {
+		return 0, fmt.Errorf("tz: invalid offset %q", s)
+	}
+	m := 0
+	if mm != "" {
+		if m, err = strconv.Atoi(mm); err != nil {
+			return 0, fmt.Errorf("tz: invalid offset %q", s)
+		}
+	}
+	return sign * (h*3600 + m*60), nil
+}
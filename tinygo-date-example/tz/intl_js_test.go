@@ -0,0 +1,83 @@
+//go:build js && wasm
+
+package tz
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testInstants are instants around the DST transitions of 2025 and elsewhere in the year.
+func testInstants() []time.Time {
+	var ts []time.Time
+	for t := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC); t.Year() == 2025; t = t.Add(61 * time.Hour) {
+		ts = append(ts, t)
+	}
+	return append(ts,
+		time.Date(2025, 3, 9, 6, 59, 59, 0, time.UTC), // just before DST in New York
+		time.Date(2025, 3, 9, 7, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 30, 0, 59, 59, 0, time.UTC), // just before DST in the EU
+		time.Date(2025, 3, 30, 1, 0, 0, 0, time.UTC),
+		time.Date(2025, 4, 5, 15, 59, 59, 0, time.UTC), // just before the end of DST in Sydney
+		time.Date(2025, 4, 5, 16, 0, 0, 0, time.UTC),
+	)
+}
+
+func TestIntlMatchesTable(t *testing.T) {
+	for _, name := range (Table{}).Names() {
+		for _, ts := range testInstants() {
+			want, err := Table{}.Zone(name, ts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loc, err := Intl{}.Zone(name, ts)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			_, gotOffset := ts.In(loc).Zone()
+			_, wantOffset := ts.In(want).Zone()
+			if gotOffset != wantOffset {
+				t.Errorf("%s at %s: intl offset %d, table offset %d", name, ts.Format(time.RFC3339), gotOffset, wantOffset)
+			}
+		}
+	}
+}
+
+func TestIntlUnknownZone(t *testing.T) {
+	for _, name := range []string{"", "Mars/Olympus_Mons", "Local"} {
+		if _, err := (Intl{}).Zone(name, time.Now()); !errors.Is(err, ErrUnknownZone) {
+			t.Errorf("%q: err = %v, want ErrUnknownZone", name, err)
+		}
+	}
+}
+
+func TestIntlAbbreviation(t *testing.T) {
+	ts := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	for name, want := range map[string]string{
+		"America/New_York": "EST",
+		"Asia/Kolkata":     "+0530",
+		"UTC":              "UTC",
+	} {
+		loc, err := Intl{}.Zone(name, ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := ts.In(loc).Zone(); got != want {
+			t.Errorf("%s: abbreviation %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestParseGMTOffset(t *testing.T) {
+	for s, want := range map[string]int{"GMT": 0, "GMT+09:00": 9 * 3600, "GMT-03:30": -(3*3600 + 30*60), "GMT+5": 5 * 3600} {
+		if got, err := parseGMTOffset(s); err != nil || got != want {
+			t.Errorf("parseGMTOffset(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "UTC+1", "GMT*1", "GMT+x"} {
+		if _, err := parseGMTOffset(s); err == nil {
+			t.Errorf("parseGMTOffset(%q): want error", s)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package tz
+
+import (
+	"fmt"
+	"time"
+)
+
+// Table is a Source of a trimmed zone table built into the binary.
+//
+// TinyGo cannot embed the time zone database, so the table only has common zones
+// with their current rules. Past rule changes are not recorded, and the locations
+// are fixed zones which are only valid at the given time.
+type Table struct{}
+
+func (Table) Name() string {
+	return "table"
+}
+
+func (Table) Zone(name string, t time.Time) (*time.Location, error) {
+	z, ok := zones[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownZone, name)
+	}
+	if z.dst != nil && z.dst.active(t, z.offset) {
+		return time.FixedZone(z.dstAbbr, z.offset+z.dst.save), nil
+	}
+	return time.FixedZone(z.abbr, z.offset), nil
+}
+
+// Names returns the names of the zones in the table.
+func (Table) Names() []string {
+	names := make([]string, 0, len(zones))
+	for name := range zones {
+		names = append(names, name)
+	}
+	return names
+}
+
+// zone is a row of the table.
+type zone struct {
+	abbr    string
+	offset  int // seconds east of UTC in standard time
+	dst     *dstRule
+	dstAbbr string
+}
+
+// transition is a yearly time of a DST transition.
+type transition struct {
+	month time.Month
+	// week is the week of the weekday in the month, from 1, or -1 for the last one.
+	week    int
+	weekday time.Weekday
+	// hour is in local standard time, or in UTC if utc is set.
+	hour int
+	utc  bool
+}
+
+// at returns the time of the transition in year for a zone with offset.
+func (tr transition) at(year, offset int) time.Time {
+	var day int
+	if tr.week > 0 {
+		first := time.Date(year, tr.month, 1, 0, 0, 0, 0, time.UTC).Weekday()
+		day = 1 + (int(tr.weekday)-int(first)+7)%7 + 7*(tr.week-1)
+	} else {
+		last := time.Date(year, tr.month+1, 0, 0, 0, 0, 0, time.UTC)
+		day = last.Day() - (int(last.Weekday())-int(tr.weekday)+7)%7
+	}
+	t := time.Date(year, tr.month, day, tr.hour, 0, 0, 0, time.UTC)
+	if !tr.utc {
+		t = t.Add(-time.Duration(offset) * time.Second)
+	}
+	return t
+}
+
+// dstRule is a yearly daylight saving time rule.
+type dstRule struct {
+	start, end transition
+	save       int // seconds added to the standard offset
+}
+
+// active reports whether DST is in effect at t for a zone with offset.
+func (r *dstRule) active(t time.Time, offset int) bool {
+	// The year in local standard time, so that transitions near New Year fall into the right year.
+	year := t.UTC().Add(time.Duration(offset) * time.Second).Year()
+	start, end := r.start.at(year, offset), r.end.at(year, offset)
+	if start.Before(end) {
+		// Northern hemisphere
+		return !t.Before(start) && t.Before(end)
+	}
+	// Southern hemisphere: DST spans New Year.
+	return !t.Before(start) || t.Before(end)
+}
+
+var (
+	// United States and Canada since 2007
+	usRule = &dstRule{
+		start: transition{month: time.March, week: 2, weekday: time.Sunday, hour: 2},
+		end:   transition{month: time.November, week: 1, weekday: time.Sunday, hour: 1},
+		save:  3600,
+	}
+	// European Union and the United Kingdom
+	euRule = &dstRule{
+		start: transition{month: time.March, week: -1, weekday: time.Sunday, hour: 1, utc: true},
+		end:   transition{month: time.October, week: -1, weekday: time.Sunday, hour: 1, utc: true},
+		save:  3600,
+	}
+	// South-eastern Australia since 2008
+	auRule = &dstRule{
+		start: transition{month: time.October, week: 1, weekday: time.Sunday, hour: 2},
+		end:   transition{month: time.April, week: 1, weekday: time.Sunday, hour: 2},
+		save:  3600,
+	}
+	// New Zealand since 2007
+	nzRule = &dstRule{
+		start: transition{month: time.September, week: -1, weekday: time.Sunday, hour: 2},
+		end:   transition{month: time.April, week: 1, weekday: time.Sunday, hour: 2},
+		save:  3600,
+	}
+)
+
+const hour = 3600
+
+var zones = map[string]zone{
+	"UTC":     {abbr: "UTC"},
+	"Etc/UTC": {abbr: "UTC"},
+
+	"Asia/Tokyo":     {abbr: "JST", offset: 9 * hour},
+	"Asia/Seoul":     {abbr: "KST", offset: 9 * hour},
+	"Asia/Shanghai":  {abbr: "CST", offset: 8 * hour},
+	"Asia/Hong_Kong": {abbr: "HKT", offset: 8 * hour},
+	"Asia/Taipei":    {abbr: "CST", offset: 8 * hour},
+	"Asia/Singapore": {abbr: "+08", offset: 8 * hour},
+	"Asia/Bangkok":   {abbr: "+07", offset: 7 * hour},
+	"Asia/Jakarta":   {abbr: "WIB", offset: 7 * hour},
+	"Asia/Kolkata":   {abbr: "IST", offset: 5*hour + 1800},
+	"Asia/Kathmandu": {abbr: "+0545", offset: 5*hour + 2700},
+	"Asia/Karachi":   {abbr: "PKT", offset: 5 * hour},
+	"Asia/Dubai":     {abbr: "+04", offset: 4 * hour},
+	"Asia/Tehran":    {abbr: "+0330", offset: 3*hour + 1800},
+
+	"Europe/London":    {abbr: "GMT", offset: 0, dst: euRule, dstAbbr: "BST"},
+	"Europe/Lisbon":    {abbr: "WET", offset: 0, dst: euRule, dstAbbr: "WEST"},
+	"Europe/Paris":     {abbr: "CET", offset: 1 * hour, dst: euRule, dstAbbr: "CEST"},
+	"Europe/Berlin":    {abbr: "CET", offset: 1 * hour, dst: euRule, dstAbbr: "CEST"},
+	"Europe/Madrid":    {abbr: "CET", offset: 1 * hour, dst: euRule, dstAbbr: "CEST"},
+	"Europe/Rome":      {abbr: "CET", offset: 1 * hour, dst: euRule, dstAbbr: "CEST"},
+	"Europe/Amsterdam": {abbr: "CET", offset: 1 * hour, dst: euRule, dstAbbr: "CEST"},
+	"Europe/Stockholm": {abbr: "CET", offset: 1 * hour, dst: euRule, dstAbbr: "CEST"},
+	"Europe/Warsaw":    {abbr: "CET", offset: 1 * hour, dst: euRule, dstAbbr: "CEST"},
+	"Europe/Zurich":    {abbr: "CET", offset: 1 * hour, dst: euRule, dstAbbr: "CEST"},
+	"Europe/Athens":    {abbr: "EET", offset: 2 * hour, dst: euRule, dstAbbr: "EEST"},
+	"Europe/Helsinki":  {abbr: "EET", offset: 2 * hour, dst: euRule, dstAbbr: "EEST"},
+	"Europe/Kyiv":      {abbr: "EET", offset: 2 * hour, dst: euRule, dstAbbr: "EEST"},
+	"Europe/Istanbul":  {abbr: "+03", offset: 3 * hour},
+	"Europe/Moscow":    {abbr: "MSK", offset: 3 * hour},
+
+	"America/New_York":    {abbr: "EST", offset: -5 * hour, dst: usRule, dstAbbr: "EDT"},
+	"America/Toronto":     {abbr: "EST", offset: -5 * hour, dst: usRule, dstAbbr: "EDT"},
+	"America/Chicago":     {abbr: "CST", offset: -6 * hour, dst: usRule, dstAbbr: "CDT"},
+	"America/Denver":      {abbr: "MST", offset: -7 * hour, dst: usRule, dstAbbr: "MDT"},
+	"America/Phoenix":     {abbr: "MST", offset: -7 * hour},
+	"America/Los_Angeles": {abbr: "PST", offset: -8 * hour, dst: usRule, dstAbbr: "PDT"},
+	"America/Vancouver":   {abbr: "PST", offset: -8 * hour, dst: usRule, dstAbbr: "PDT"},
+	"America/Anchorage":   {abbr: "AKST", offset: -9 * hour, dst: usRule, dstAbbr: "AKDT"},
+	"America/Mexico_City": {abbr: "CST", offset: -6 * hour},
+	"America/Bogota":      {abbr: "-05", offset: -5 * hour},
+	"America/Lima":        {abbr: "-05", offset: -5 * hour},
+	"America/Sao_Paulo":   {abbr: "-03", offset: -3 * hour},
+	"Pacific/Honolulu":    {abbr: "HST", offset: -10 * hour},
+
+	"America/Argentina/Buenos_Aires": {abbr: "-03", offset: -3 * hour},
+
+	"Africa/Lagos":        {abbr: "WAT", offset: 1 * hour},
+	"Africa/Johannesburg": {abbr: "SAST", offset: 2 * hour},
+	"Africa/Nairobi":      {abbr: "EAT", offset: 3 * hour},
+
+	"Australia/Perth":     {abbr: "AWST", offset: 8 * hour},
+	"Australia/Darwin":    {abbr: "ACST", offset: 9*hour + 1800},
+	"Australia/Adelaide":  {abbr: "ACST", offset: 9*hour + 1800, dst: auRule, dstAbbr: "ACDT"},
+	"Australia/Brisbane":  {abbr: "AEST", offset: 10 * hour},
+	"Australia/Sydney":    {abbr: "AEST", offset: 10 * hour, dst: auRule, dstAbbr: "AEDT"},
+	"Australia/Melbourne": {abbr: "AEST", offset: 10 * hour, dst: auRule, dstAbbr: "AEDT"},
+	"Pacific/Auckland":    {abbr: "NZST", offset: 12 * hour, dst: nzRule, dstAbbr: "NZDT"},
+}
@@ -0,0 +1,51 @@
+//go:build !tinygo
+
+package tz
+
+import (
+	"testing"
+	"time"
+)
+
+// years are the years checked against the time zone database of Go.
+// The table only has current rules, so it is not compared with earlier years.
+var years = []int{2024, 2025, 2026, 2027}
+
+// tableInstants are instants every 61 hours through years, and around every transition of the rules.
+func tableInstants(offset int, rule *dstRule) []time.Time {
+	var ts []time.Time
+	for t := time.Date(years[0], 1, 1, 0, 0, 0, 0, time.UTC); t.Year() <= years[len(years)-1]; t = t.Add(61 * time.Hour) {
+		ts = append(ts, t)
+	}
+	if rule != nil {
+		for _, y := range years {
+			for _, tr := range []transition{rule.start, rule.end} {
+				at := tr.at(y, offset)
+				ts = append(ts, at.Add(-time.Second), at, at.Add(time.Second))
+			}
+		}
+	}
+	return ts
+}
+
+func TestTableMatchesTZData(t *testing.T) {
+	for name, z := range zones {
+		want, err := time.LoadLocation(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		for _, ts := range tableInstants(z.offset, z.dst) {
+			loc, err := Table{}.Zone(name, ts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotAbbr, gotOffset := ts.In(loc).Zone()
+			wantAbbr, wantOffset := ts.In(want).Zone()
+			if gotAbbr != wantAbbr || gotOffset != wantOffset {
+				t.Errorf("%s at %s: table %s %d, tzdata %s %d", name, ts.Format(time.RFC3339), gotAbbr, gotOffset, wantAbbr, wantOffset)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package tz
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		t        time.Time
+		wantAbbr string
+		wantOff  int
+	}{
+		{"Asia/Tokyo", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), "JST", 9 * hour},
+		{"America/New_York", time.Date(2025, 3, 9, 6, 59, 59, 0, time.UTC), "EST", -5 * hour},
+		{"America/New_York", time.Date(2025, 3, 9, 7, 0, 0, 0, time.UTC), "EDT", -4 * hour},
+		{"America/New_York", time.Date(2025, 11, 2, 5, 59, 59, 0, time.UTC), "EDT", -4 * hour},
+		{"America/New_York", time.Date(2025, 11, 2, 6, 0, 0, 0, time.UTC), "EST", -5 * hour},
+		{"Europe/Berlin", time.Date(2025, 3, 30, 1, 0, 0, 0, time.UTC), "CEST", 2 * hour},
+		{"Europe/Berlin", time.Date(2025, 10, 26, 1, 0, 0, 0, time.UTC), "CET", 1 * hour},
+		{"Australia/Sydney", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), "AEDT", 11 * hour},
+		{"Australia/Sydney", time.Date(2025, 4, 5, 16, 0, 0, 0, time.UTC), "AEST", 10 * hour},
+		{"Australia/Sydney", time.Date(2025, 10, 4, 16, 0, 0, 0, time.UTC), "AEDT", 11 * hour},
+		{"Pacific/Auckland", time.Date(2025, 9, 27, 14, 0, 0, 0, time.UTC), "NZDT", 13 * hour},
+	}
+	for _, tt := range tests {
+		loc, err := Table{}.Zone(tt.name, tt.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if abbr, off := tt.t.In(loc).Zone(); abbr != tt.wantAbbr || off != tt.wantOff {
+			t.Errorf("%s at %s = %s %d, want %s %d", tt.name, tt.t.Format(time.RFC3339), abbr, off, tt.wantAbbr, tt.wantOff)
+		}
+	}
+	if _, err := (Table{}).Zone("Mars/Olympus_Mons", time.Now()); !errors.Is(err, ErrUnknownZone) {
+		t.Errorf("unknown zone: err = %v", err)
+	}
+}
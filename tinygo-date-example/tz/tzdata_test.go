@@ -0,0 +1,6 @@
+//go:build !tinygo
+
+package tz
+
+// The tests compare the table with the time zone database of Go.
+import _ "time/tzdata"
@@ -1,20 +1,74 @@
 package main
 
 import (
-	"html/template"
+	"errors"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/syumai/workers"
+
+	"github.com/syumai/workers-playground/tinygo-html-template/tinytmpl"
 )
 
+// counterTmpl is parsed once at startup. tinytmpl is used instead of html/template
+// because it works identically under TinyGo and keeps the Wasm binary small.
+var counterTmpl = tinytmpl.Must(tinytmpl.Parse("counter", `<div>{{.CounterValue}}</div>`))
+
+var (
+	streamHead = tinytmpl.Must(tinytmpl.Parse("stream-head", `<!doctype html>
+<html lang="en">
+<head><meta charset="utf-8"><title>{{.title}}</title></head>
+<body>
+<h1>{{.title}}</h1>
+`))
+	streamSection = tinytmpl.Must(tinytmpl.Parse("stream-section", `<section>
+  <h2>{{.name}}</h2>
+  <p>Resolved in {{.elapsed}}.</p>
+</section>
+`))
+	streamTail = tinytmpl.Must(tinytmpl.Parse("stream-tail", `</body>
+</html>
+`))
+)
+
+// delayed returns section data resolved after d, standing in for a slow backend.
+func delayed(start time.Time, name string, d time.Duration) func() (any, error) {
+	return func() (any, error) {
+		time.Sleep(d)
+		return map[string]any{
+			"name":    name,
+			"elapsed": time.Since(start).Round(time.Millisecond).String(),
+		}, nil
+	}
+}
+
 func main() {
 	http.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
-		tmpl, _ := template.New("foo").Parse(`<div>{{.CounterValue}}</div>`)
-
-		data := map[string]int{
+		data := map[string]any{
 			"CounterValue": 10,
 		}
-		_ = tmpl.ExecuteTemplate(w, "foo", data)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		// Execute writes nothing on error, so an error page can still be sent.
+		if err := counterTmpl.Execute(w, data); err != nil {
+			log.Printf("render counter: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	})
+	http.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := tinytmpl.Stream(w, streamHead, map[string]any{"title": "Streaming"}, []tinytmpl.Section{
+			{Template: streamSection, Data: delayed(start, "Fast section", 300*time.Millisecond)},
+			{Template: streamSection, Data: delayed(start, "Slow section", time.Second)},
+		}, streamTail)
+		if errors.Is(err, tinytmpl.ErrAfterHead) {
+			// The head is already sent, so the error can only be logged.
+			log.Printf("render stream: %v", err)
+		} else if err != nil {
+			log.Printf("render stream: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
 	})
 	workers.Serve(nil) // use http.DefaultServeMux
 }
@@ -0,0 +1,12 @@
+// Package sizecheck holds the Wasm binary size checks of this example.
+//
+// The tests build the main package with TinyGo and Go, report both sizes, and
+// fail if the TinyGo binary exceeds the budget. They are skipped if TinyGo is not installed.
+//
+//	go test ./sizecheck -v
+//
+// The budget is TinyGoBudget bytes, or the TINYGO_WASM_BUDGET environment variable if set.
+package sizecheck
+
+// TinyGoBudget is the maximum size of the TinyGo Wasm binary in bytes.
+const TinyGoBudget = 1536 << 10
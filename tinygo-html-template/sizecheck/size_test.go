@@ -0,0 +1,69 @@
+package sizecheck
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// build builds the main package with the command and returns the size of the Wasm binary.
+func build(t *testing.T, name string, args []string, env ...string) int64 {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), name+".wasm")
+	cmd := exec.Command(args[0], append(args[1:], "-o", out, ".")...)
+	cmd.Dir = ".."
+	cmd.Env = append(os.Environ(), env...)
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s build failed: %v\n%s", name, err, b)
+	}
+	fi, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fi.Size()
+}
+
+func tinygoSize(t *testing.T) int64 {
+	t.Helper()
+	if _, err := exec.LookPath("tinygo"); err != nil {
+		t.Skip("tinygo is not installed")
+	}
+	// The same flags and main package as the build target of the Makefile.
+	return build(t, "tinygo", []string{"tinygo", "build", "-target", "wasm", "-no-debug"})
+}
+
+func budget(t *testing.T) int64 {
+	t.Helper()
+	s := os.Getenv("TINYGO_WASM_BUDGET")
+	if s == "" {
+		return TinyGoBudget
+	}
+	b, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		t.Fatalf("invalid TINYGO_WASM_BUDGET: %v", err)
+	}
+	return b
+}
+
+func TestTinyGoBudget(t *testing.T) {
+	size, limit := tinygoSize(t), budget(t)
+	if size > limit {
+		t.Fatalf("TinyGo Wasm is %s, over the budget of %s", formatSize(size), formatSize(limit))
+	}
+	t.Logf("TinyGo Wasm is %s, %.1f%% of the budget of %s", formatSize(size), 100*float64(size)/float64(limit), formatSize(limit))
+}
+
+// TestSizeReport logs the sizes of the binaries built by TinyGo and Go as a Markdown table.
+func TestSizeReport(t *testing.T) {
+	tinygo := tinygoSize(t)
+	gc := build(t, "go", []string{"go", "build", "-trimpath", "-ldflags=-s -w"}, "GOOS=js", "GOARCH=wasm")
+	t.Logf("\n| Toolchain | Wasm size | Ratio |\n| --- | ---: | ---: |\n| TinyGo | %s | %.2f |\n| Go | %s | 1.00 |",
+		formatSize(tinygo), float64(tinygo)/float64(gc), formatSize(gc))
+}
+
+func formatSize(n int64) string {
+	return fmt.Sprintf("%d bytes (%.1f KiB)", n, float64(n)/1024)
+}
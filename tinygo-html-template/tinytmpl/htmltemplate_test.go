@@ -0,0 +1,31 @@
+//go:build !tinygo
+
+package tinytmpl
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+)
+
+// TestSameAsHTMLTemplate checks that the supported subset renders the same as html/template.
+// It is excluded from TinyGo, whose support of html/template is the reason for this package.
+func TestSameAsHTMLTemplate(t *testing.T) {
+	// html/template has its own type for trusted HTML.
+	data := make(map[string]any, len(testData))
+	for k, v := range testData {
+		data[k] = v
+	}
+	data["trusted"] = template.HTML(testData["trusted"].(HTML))
+	for _, tt := range subset {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := template.Must(template.New(tt.name).Parse(tt.text)).Execute(&got, data); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package tinytmpl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrAfterHead is wrapped by every error of Stream returned after the head was written.
+// The response is then partially sent, so such errors can only be logged.
+var ErrAfterHead = errors.New("tinytmpl: failed after the head was written")
+
+// ErrSection is wrapped by the error of Stream when a section failed. It always comes with ErrAfterHead.
+var ErrSection = errors.New("tinytmpl: section failed")
+
+// Section is a part of a streamed page. Its data is resolved while the
+// preceding parts are already sent to the client.
+type Section struct {
+	Template *Template
+	// Data resolves the data of the section.
+	Data func() (any, error)
+}
+
+// Stream writes head with data, each section, and tail with data, each as soon as it is rendered.
+// A slow section does not delay the first byte of the response.
+//
+// The ResponseWriter of Workers sends every write through the ReadableStream of the response
+// and does not implement http.Flusher. For other writers implementing it, such as those of
+// net/http servers, w is flushed after each part.
+//
+// The data of all sections are resolved concurrently after the head is written.
+// If a section fails, an error wrapping ErrSection is returned after writing tail, so the document
+// is still complete; the remaining sections are skipped. Every error after the head is written,
+// including one of tail joined with that of a section, wraps ErrAfterHead. Nothing is written if
+// head fails, so an error page can still be sent.
+func Stream(w io.Writer, head *Template, data any, sections []Section, tail *Template) error {
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if err := head.Execute(w, data); err != nil {
+		return err
+	}
+	flush()
+
+	type result struct {
+		data any
+		err  error
+	}
+	results := make([]chan result, len(sections))
+	for i, s := range sections {
+		results[i] = make(chan result, 1)
+		go func(s Section, ch chan<- result) {
+			data, err := s.Data()
+			ch <- result{data: data, err: err}
+		}(s, results[i])
+	}
+	var sectionErr error
+	for i, s := range sections {
+		res := <-results[i]
+		err := res.err
+		if err == nil {
+			err = s.Template.Execute(w, res.data)
+		}
+		if err != nil {
+			sectionErr = fmt.Errorf("%w: %w: %s: %w", ErrAfterHead, ErrSection, s.Template.Name(), err)
+			break
+		}
+		flush()
+	}
+
+	if err := tail.Execute(w, data); err != nil {
+		return errors.Join(sectionErr, fmt.Errorf("%w: %w", ErrAfterHead, err))
+	}
+	flush()
+	return sectionErr
+}
@@ -0,0 +1,106 @@
+package tinytmpl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// chunkWriter records the output written between flushes as chunks.
+type chunkWriter struct {
+	chunks  []string
+	pending strings.Builder
+	flushed chan string
+}
+
+func (c *chunkWriter) Write(b []byte) (int, error) {
+	return c.pending.Write(b)
+}
+
+func (c *chunkWriter) Flush() {
+	chunk := c.pending.String()
+	c.pending.Reset()
+	c.chunks = append(c.chunks, chunk)
+	c.flushed <- chunk
+}
+
+func TestStream(t *testing.T) {
+	head := Must(Parse("head", `<head><title>{{.title}}</title></head><main>`))
+	tail := Must(Parse("tail", `</main>`))
+	section := Must(Parse("section", `<section>{{.}}</section>`))
+
+	w := &chunkWriter{flushed: make(chan string, 8)}
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- Stream(w, head, map[string]any{"title": "T"}, []Section{
+			{Template: section, Data: func() (any, error) {
+				<-release
+				return "<first>", nil
+			}},
+			{Template: section, Data: func() (any, error) { return "second", nil }},
+		}, tail)
+	}()
+
+	// The head is flushed before the data of the first section is resolved.
+	if got, want := <-w.flushed, "<head><title>T</title></head><main>"; got != want {
+		t.Errorf("head chunk = %q, want %q", got, want)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"<head><title>T</title></head><main>",
+		"<section>&lt;first&gt;</section>",
+		"<section>second</section>",
+		"</main>",
+	}
+	if strings.Join(w.chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("chunks =\n%q\nwant\n%q", w.chunks, want)
+	}
+}
+
+func TestStreamSectionError(t *testing.T) {
+	head := Must(Parse("head", `<main>`))
+	tail := Must(Parse("tail", `</main>`))
+	section := Must(Parse("section", `<section>{{.}}</section>`))
+
+	w := &chunkWriter{flushed: make(chan string, 8)}
+	err := Stream(w, head, nil, []Section{
+		{Template: section, Data: func() (any, error) { return "ok", nil }},
+		{Template: section, Data: func() (any, error) { return nil, errors.New("backend down") }},
+		{Template: section, Data: func() (any, error) { return "skipped", nil }},
+	}, tail)
+	if !errors.Is(err, ErrSection) || !errors.Is(err, ErrAfterHead) || !strings.Contains(err.Error(), "backend down") {
+		t.Errorf("err = %v", err)
+	}
+	want := []string{"<main>", "<section>ok</section>", "</main>"}
+	if strings.Join(w.chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("chunks =\n%q\nwant\n%q", w.chunks, want)
+	}
+}
+
+func TestStreamTailError(t *testing.T) {
+	head := Must(Parse("head", `<main>`))
+	tail := Must(Parse("tail", `</main>{{.missing.field}}`))
+	section := Must(Parse("section", `<section>{{.}}</section>`))
+	ok := Section{Template: section, Data: func() (any, error) { return "ok", nil }}
+	failed := Section{Template: section, Data: func() (any, error) { return nil, errors.New("backend down") }}
+
+	err := Stream(&chunkWriter{flushed: make(chan string, 8)}, head, nil, []Section{ok}, tail)
+	if !errors.Is(err, ErrAfterHead) || errors.Is(err, ErrSection) {
+		t.Errorf("tail error: err = %v, want ErrAfterHead only", err)
+	}
+
+	// The error of the section is kept along with that of the tail.
+	err = Stream(&chunkWriter{flushed: make(chan string, 8)}, head, nil, []Section{ok, failed}, tail)
+	if !errors.Is(err, ErrAfterHead) || !errors.Is(err, ErrSection) || !strings.Contains(err.Error(), "backend down") || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("section and tail errors: err = %v", err)
+	}
+
+	err = Stream(&chunkWriter{flushed: make(chan string, 8)}, Must(Parse("head", `{{.missing.field}}`)), nil, []Section{ok}, tail)
+	if err == nil || errors.Is(err, ErrAfterHead) {
+		t.Errorf("head error: err = %v, want an error before the head is written", err)
+	}
+}
@@ -0,0 +1,446 @@
+// Package tinytmpl is a small HTML template engine without reflection.
+//
+// It implements a subset of html/template which behaves identically when
+// compiled with TinyGo and Go, and keeps TinyGo Wasm binaries small.
+// For the supported subset, it renders the same output as html/template.
+//
+// # Data
+//
+// Data is a tree of map[string]any. Values may be string, HTML, bool,
+// int, int64, float64, nil, map[string]any, []any, []map[string]any, []string or []int.
+//
+// # Actions
+//
+//	{{.}}                     the current value
+//	{{.a.b}}                  a key of the current map; a missing key renders nothing
+//	{{$}} {{$.a.b}}           the data passed to Execute
+//	{{if .a}} T {{end}}       T if .a is true (non-zero, non-empty)
+//	{{if .a}} T {{else}} E {{end}}
+//	{{range .a}} T {{end}}    T for each element of a slice, with dot set to the element
+//	{{range .a}} T {{else}} E {{end}}
+//	{{/* comment */}}
+//
+// Trim markers ("{{- " and " -}}") remove the surrounding white space as in text/template.
+//
+// # Escaping
+//
+// Values are escaped as html/template escapes HTML text and quoted attribute values,
+// except values of type HTML, which are trusted and written as is.
+// Unlike html/template, escaping does not depend on the context, so values
+// must not be used in <script>, <style>, URL attributes such as href, or unquoted attributes.
+//
+// Functions, pipelines, variables, with, define, template and block are not supported.
+package tinytmpl
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// HTML is trusted HTML which is written without escaping.
+type HTML string
+
+// Template is a parsed template.
+type Template struct {
+	name  string
+	nodes []node
+}
+
+type nodeKind int
+
+const (
+	textNode nodeKind = iota
+	valueNode
+	ifNode
+	rangeNode
+)
+
+type node struct {
+	kind nodeKind
+	text string // textNode
+	path path   // valueNode, ifNode, rangeNode
+	list []node // ifNode, rangeNode
+	alt  []node // ifNode, rangeNode: {{else}}
+}
+
+// path is a parsed reference such as ".a.b" or "$.a".
+type path struct {
+	root bool
+	keys []string
+}
+
+// Must panics if err is not nil. It is for variable initialization.
+func Must(t *Template, err error) *Template {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Parse parses text as a template named name.
+func Parse(name, text string) (*Template, error) {
+	p := &parser{name: name, text: text, line: 1}
+	nodes, end, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, p.errorf("unexpected {{%s}}", end)
+	}
+	return &Template{name: name, nodes: nodes}, nil
+}
+
+// Name returns the name of the template.
+func (t *Template) Name() string {
+	return t.name
+}
+
+// spaceChars are the white space characters removed by trim markers.
+const spaceChars = " \t\r\n"
+
+type parser struct {
+	name string
+	text string
+	pos  int
+	line int
+	// trimLeft is set when the previous action ended with a trim marker.
+	trimLeft bool
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("template: %s:%d: %s", p.name, p.line, fmt.Sprintf(format, args...))
+}
+
+// parseList parses nodes until {{else}}, {{end}} or the end of text, and returns the keyword it stopped at.
+func (p *parser) parseList() ([]node, string, error) {
+	var nodes []node
+	for {
+		text, action, ok := p.next()
+		if text != "" {
+			nodes = append(nodes, node{kind: textNode, text: text})
+		}
+		if !ok {
+			return nodes, "", nil
+		}
+		if action.err != nil {
+			return nil, "", action.err
+		}
+		switch action.keyword {
+		case "":
+			nodes = append(nodes, node{kind: valueNode, path: action.path})
+		case "comment":
+		case "else", "end":
+			return nodes, action.keyword, nil
+		case "if", "range":
+			n := node{kind: ifNode, path: action.path}
+			if action.keyword == "range" {
+				n.kind = rangeNode
+			}
+			list, end, err := p.parseList()
+			if err != nil {
+				return nil, "", err
+			}
+			n.list = list
+			if end == "else" {
+				if n.alt, end, err = p.parseList(); err != nil {
+					return nil, "", err
+				}
+				if end == "else" {
+					return nil, "", p.errorf("multiple {{else}} in {{%s}}", action.keyword)
+				}
+			}
+			if end != "end" {
+				return nil, "", p.errorf("unclosed {{%s}}", action.keyword)
+			}
+			nodes = append(nodes, n)
+		}
+	}
+}
+
+type action struct {
+	keyword string
+	path    path
+	err     error
+}
+
+// next returns the text before the next action and the action, with trim markers applied.
+// ok is false at the end of text.
+func (p *parser) next() (text string, a action, ok bool) {
+	rest := p.text[p.pos:]
+	trimLeft := p.trimLeft
+	p.trimLeft = false
+	i := strings.Index(rest, "{{")
+	if i < 0 {
+		p.pos = len(p.text)
+		p.line += strings.Count(rest, "\n")
+		if trimLeft {
+			rest = strings.TrimLeft(rest, spaceChars)
+		}
+		return rest, action{}, false
+	}
+	text = rest[:i]
+	p.line += strings.Count(text, "\n")
+	if trimLeft {
+		text = strings.TrimLeft(text, spaceChars)
+	}
+	body := rest[i+2:]
+	if len(body) >= 2 && body[0] == '-' && strings.ContainsRune(spaceChars, rune(body[1])) {
+		text = strings.TrimRight(text, spaceChars)
+		body = body[1:]
+	}
+	j := strings.Index(body, "}}")
+	if j < 0 {
+		p.pos = len(p.text)
+		return text, action{err: p.errorf("unclosed action")}, true
+	}
+	p.pos = len(p.text) - len(body) + j + 2
+	inner := body[:j]
+	if n := len(inner); n >= 2 && inner[n-1] == '-' && strings.ContainsRune(spaceChars, rune(inner[n-2])) {
+		p.trimLeft = true
+		inner = inner[:n-1]
+	}
+	a = p.parseAction(inner)
+	p.line += strings.Count(inner, "\n")
+	return text, a, true
+}
+
+func (p *parser) parseAction(inner string) action {
+	s := strings.TrimSpace(inner)
+	if strings.HasPrefix(s, "/*") {
+		if !strings.HasSuffix(s, "*/") {
+			return action{err: p.errorf("unclosed comment")}
+		}
+		return action{keyword: "comment"}
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return action{err: p.errorf("missing value for command")}
+	}
+	switch fields[0] {
+	case "else", "end":
+		if len(fields) != 1 {
+			return action{err: p.errorf("unexpected %q in {{%s}}", fields[1], fields[0])}
+		}
+		return action{keyword: fields[0]}
+	case "if", "range":
+		if len(fields) != 2 {
+			return action{err: p.errorf("{{%s}} takes one value", fields[0])}
+		}
+		pa, err := p.parsePath(fields[1])
+		return action{keyword: fields[0], path: pa, err: err}
+	}
+	if len(fields) != 1 {
+		return action{err: p.errorf("unsupported action: {{%s}}", s)}
+	}
+	pa, err := p.parsePath(fields[0])
+	return action{path: pa, err: err}
+}
+
+func (p *parser) parsePath(s string) (path, error) {
+	var pa path
+	if strings.HasPrefix(s, "$") {
+		pa.root = true
+		s = s[1:]
+		if s == "" {
+			return pa, nil
+		}
+	}
+	if s == "." {
+		return pa, nil
+	}
+	if !strings.HasPrefix(s, ".") {
+		return path{}, p.errorf("unsupported value: %q", s)
+	}
+	for _, key := range strings.Split(s[1:], ".") {
+		if key == "" {
+			return path{}, p.errorf("invalid field: %q", s)
+		}
+		pa.keys = append(pa.keys, key)
+	}
+	return pa, nil
+}
+
+// Execute applies the template to data and writes the output to w.
+func (t *Template) Execute(w io.Writer, data any) error {
+	var b strings.Builder
+	s := &state{t: t, w: &b, root: data}
+	if err := s.walk(t.nodes, data); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type state struct {
+	t    *Template
+	w    *strings.Builder
+	root any
+}
+
+func (s *state) walk(nodes []node, dot any) error {
+	for _, n := range nodes {
+		switch n.kind {
+		case textNode:
+			s.w.WriteString(n.text)
+		case valueNode:
+			v, err := s.eval(n.path, dot)
+			if err != nil {
+				return err
+			}
+			if err := writeValue(s.w, v); err != nil {
+				return fmt.Errorf("template: %s: %w", s.t.name, err)
+			}
+		case ifNode:
+			v, err := s.eval(n.path, dot)
+			if err != nil {
+				return err
+			}
+			list := n.alt
+			if truth(v) {
+				list = n.list
+			}
+			if err := s.walk(list, dot); err != nil {
+				return err
+			}
+		case rangeNode:
+			v, err := s.eval(n.path, dot)
+			if err != nil {
+				return err
+			}
+			items, err := elements(v)
+			if err != nil {
+				return fmt.Errorf("template: %s: %w", s.t.name, err)
+			}
+			if len(items) == 0 {
+				if err := s.walk(n.alt, dot); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, item := range items {
+				if err := s.walk(n.list, item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *state) eval(p path, dot any) (any, error) {
+	v := dot
+	if p.root {
+		v = s.root
+	}
+	for i, key := range p.keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			if v == nil {
+				return nil, fmt.Errorf("template: %s: nil value evaluating .%s", s.t.name, strings.Join(p.keys[:i+1], "."))
+			}
+			return nil, fmt.Errorf("template: %s: can't evaluate field %s in type %T", s.t.name, key, v)
+		}
+		v = m[key]
+	}
+	return v, nil
+}
+
+// truth reports whether v is true in the sense of {{if}}.
+func truth(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case HTML:
+		return v != ""
+	case int:
+		return v != 0
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case map[string]any:
+		return len(v) > 0
+	}
+	items, err := elements(v)
+	return err != nil || len(items) > 0
+}
+
+// elements returns the elements of a slice for {{range}}.
+func elements(v any) ([]any, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		return v, nil
+	case []map[string]any:
+		items := make([]any, len(v))
+		for i, e := range v {
+			items[i] = e
+		}
+		return items, nil
+	case []string:
+		items := make([]any, len(v))
+		for i, e := range v {
+			items[i] = e
+		}
+		return items, nil
+	case []int:
+		items := make([]any, len(v))
+		for i, e := range v {
+			items[i] = e
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("range can't iterate over %T", v)
+}
+
+func writeValue(b *strings.Builder, v any) error {
+	switch v := v.(type) {
+	case nil:
+	case HTML:
+		b.WriteString(string(v))
+	case string:
+		escape(b, v)
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case int:
+		b.WriteString(strconv.Itoa(v))
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		escape(b, strconv.FormatFloat(v, 'g', -1, 64))
+	default:
+		return fmt.Errorf("can't print value of type %T", v)
+	}
+	return nil
+}
+
+// escape writes s escaped as html/template escapes HTML text.
+func escape(b *strings.Builder, s string) {
+	for _, r := range s {
+		switch r {
+		case 0:
+			b.WriteString("\uFFFD")
+		case '"':
+			b.WriteString("&#34;")
+		case '&':
+			b.WriteString("&amp;")
+		case '\'':
+			b.WriteString("&#39;")
+		case '+':
+			b.WriteString("&#43;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package tinytmpl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var testData = map[string]any{
+	"title":   `Tom & Jerry's <"show"> 1+1`,
+	"count":   10,
+	"big":     int64(1) << 40,
+	"ratio":   1.5,
+	"huge":    1e21,
+	"ok":      true,
+	"no":      false,
+	"zero":    0,
+	"empty":   "",
+	"nul":     "a\x00b",
+	"trusted": HTML("<b>bold</b>"),
+	"user": map[string]any{
+		"name": "<script>alert(1)</script>",
+		"tags": []string{"go", "tinygo"},
+	},
+	"items": []map[string]any{
+		{"name": "a", "price": 1},
+		{"name": "b", "price": 2, "sale": true},
+	},
+	"mixed":   []any{"x", 1, true},
+	"numbers": []int{1, 2, 3},
+	"none":    []any{},
+}
+
+// subset is the supported subset of templates with the expected output for testData.
+// html/template renders the same output (see htmltemplate_test.go).
+var subset = []struct {
+	name string
+	text string
+	want string
+}{
+	{"text", `plain <b>text</b>`, `plain <b>text</b>`},
+	{"string", `<p>{{.title}}</p>`, `<p>Tom &amp; Jerry&#39;s &lt;&#34;show&#34;&gt; 1&#43;1</p>`},
+	{"attribute", `<input value="{{.title}}" title='{{.user.name}}'>`, `<input value="Tom &amp; Jerry&#39;s &lt;&#34;show&#34;&gt; 1&#43;1" title='&lt;script&gt;alert(1)&lt;/script&gt;'>`},
+	{"numbers", `{{.count}} {{.big}} {{.ratio}} {{.huge}} {{.zero}}`, `10 1099511627776 1.5 1e&#43;21 0`},
+	{"bools", `{{.ok}} {{.no}}`, `true false`},
+	{"nul", `{{.nul}}`, "a\uFFFDb"},
+	{"trusted", `{{.trusted}}`, `<b>bold</b>`},
+	{"nested", `{{.user.name}}`, `&lt;script&gt;alert(1)&lt;/script&gt;`},
+	{"missing", `[{{.missing}}]`, `[]`},
+	{"dot", `{{range .numbers}}{{.}},{{end}}`, `1,2,3,`},
+	{"root", `{{range .items}}{{.name}}@{{$.title}};{{end}}{{$.count}}`, `a@Tom &amp; Jerry&#39;s &lt;&#34;show&#34;&gt; 1&#43;1;b@Tom &amp; Jerry&#39;s &lt;&#34;show&#34;&gt; 1&#43;1;10`},
+	{"if", `{{if .ok}}yes{{end}}{{if .no}}no{{end}}{{if .zero}}zero{{end}}{{if .empty}}empty{{end}}{{if .missing}}missing{{end}}{{if .items}}items{{end}}{{if .none}}none{{end}}`, `yesitems`},
+	{"if else", `{{if .no}}yes{{else}}no{{end}}`, `no`},
+	{"range", `<ul>{{range .items}}<li>{{.name}}: {{.price}}{{if .sale}} (sale){{end}}</li>{{end}}</ul>`, `<ul><li>a: 1</li><li>b: 2 (sale)</li></ul>`},
+	{"range strings", `{{range .user.tags}}#{{.}} {{end}}`, `#go #tinygo `},
+	{"range mixed", `{{range .mixed}}{{.}}|{{end}}`, `x|1|true|`},
+	{"range else", `{{range .none}}x{{else}}empty{{end}}{{range .missing}}x{{else}}missing{{end}}`, `emptymissing`},
+	{"nested blocks", `{{range .items}}{{if .sale}}{{range $.numbers}}{{.}}{{end}}{{else}}-{{end}}{{end}}`, `-123`},
+	{"comment", `a{{/* comment */}}b`, `ab`},
+	{"trim", "<ul>\n  {{- range .numbers}}\n  <li>{{.}}</li>\n  {{- end}}\n</ul>\n{{- /* trimmed */ -}}\n  !", "<ul>\n  <li>1</li>\n  <li>2</li>\n  <li>3</li>\n</ul>!"},
+	{"spaces", `{{ .count }}{{ if .ok }}y{{ end }}`, `10y`},
+	{"multiline", "line1\n{{.count}}\nline3", "line1\n10\nline3"},
+}
+
+func TestExecute(t *testing.T) {
+	for _, tt := range subset {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.name, tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			if err := tmpl.Execute(&got, testData); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`{{.a`, "t:1: unclosed action"},
+		{"\n{{if .a}}", "t:2: unclosed {{if}}"},
+		{`{{end}}`, "unexpected {{end}}"},
+		{`{{else}}`, "unexpected {{else}}"},
+		{`{{if .a}}{{else}}{{else}}{{end}}`, "multiple {{else}}"},
+		{`{{if}}{{end}}`, "{{if}} takes one value"},
+		{`{{.a | html}}`, "unsupported action"},
+		{`{{printf "%d" .a}}`, "unsupported action"},
+		{`{{with .a}}{{end}}`, "unsupported action"},
+		{`{{$x := .a}}`, "unsupported action"},
+		{`{{a}}`, "unsupported value"},
+		{`{{.a..b}}`, "invalid field"},
+		{`{{}}`, "missing value"},
+		{`{{/* open }}`, "unclosed comment"},
+	}
+	for _, tt := range tests {
+		_, err := Parse("t", tt.text)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.text, err, tt.want)
+		}
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`{{.title.x}}`, "can't evaluate field x in type string"},
+		{`{{.missing.x}}`, "nil value evaluating .missing.x"},
+		{`{{range .count}}{{end}}`, "range can't iterate over int"},
+		{`{{.user}}`, "can't print value of type map"},
+	}
+	for _, tt := range tests {
+		tmpl := Must(Parse("t", tt.text))
+		var b bytes.Buffer
+		err := tmpl.Execute(&b, testData)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Execute(%q) error = %v, want %q", tt.text, err, tt.want)
+		}
+		if b.Len() != 0 {
+			t.Errorf("Execute(%q) wrote %q on error", tt.text, b.String())
+		}
+	}
+}